@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+	"github.com/imcanugur/httpsify/internal/proxy"
+)
+
+type dashboardFlags struct {
+	cfg      *config.Config
+	server   *string
+	token    *string
+	stateDir *string
+	certPath *string
+}
+
+func addDashboardFlags(fs *flag.FlagSet) *dashboardFlags {
+	cfg := config.DefaultConfig()
+	cfg.LoadFromEnv()
+	return &dashboardFlags{
+		cfg:      cfg,
+		server:   fs.String("server", "", "Dashboard URL of the running httpsify (default https://<root host><listen port>)"),
+		token:    fs.String("token", "", "Dashboard token (default: read from --state-dir)"),
+		stateDir: fs.String("state-dir", cfg.StateDir, "Directory holding dashboard.token"),
+		certPath: fs.String("cert", cfg.CertPath, "Certificate used to verify the dashboard"),
+	}
+}
+
+func (d *dashboardFlags) call(method, path string, in, out any) error {
+	token := *d.token
+	if token == "" {
+		b, err := os.ReadFile(filepath.Join(*d.stateDir, "dashboard.token"))
+		if err != nil {
+			return fmt.Errorf("failed to read dashboard token (use --token): %w", err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	server := *d.server
+	if server == "" {
+		host := d.cfg.RootHost()
+		if port := d.cfg.ListenPort(); port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+		server = "https://" + host
+	}
+
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, strings.TrimSuffix(server, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := dashboardClient(*d.certPath).Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", server, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e proxy.ErrorResponse
+		json.NewDecoder(resp.Body).Decode(&e)
+		if e.Hint != "" {
+			return fmt.Errorf("%s (%s)", e.Error, e.Hint)
+		}
+		return fmt.Errorf("%s: %s", resp.Status, e.Error)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("invalid response: %w", err)
+		}
+	}
+	return nil
+}
+
+func dashboardClient(certPath string) *http.Client {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if pem, err := os.ReadFile(certPath); err == nil {
+		pool.AppendCertsFromPEM(pem)
+	}
+	return &http.Client{
+		Timeout: 60 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}
+}
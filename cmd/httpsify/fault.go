@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+	"github.com/imcanugur/httpsify/internal/proxy"
+)
+
+func runFault(args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	command, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("fault "+command, flag.ExitOnError)
+	dashboard := addDashboardFlags(fs)
+	rule := config.FaultRule{}
+	var methods string
+	var latency, jitter, dropAfter time.Duration
+	if command == "add" {
+		fs.StringVar(&rule.Route, "route", "", "Route name or port to match (default: all routes)")
+		fs.StringVar(&methods, "method", "", "Comma-separated methods to match")
+		fs.StringVar(&rule.Path, "path", "", "Path pattern to match (e.g., /api/*)")
+		fs.DurationVar(&latency, "latency", 0, "Added latency")
+		fs.DurationVar(&jitter, "jitter", 0, "Random extra latency up to this duration")
+		fs.Float64Var(&rule.ErrorRate, "error-rate", 0, "Percentage of requests answered with a synthetic error")
+		fs.IntVar(&rule.ErrorStatus, "error-status", http.StatusServiceUnavailable, "Status code for synthetic errors")
+		fs.Float64Var(&rule.ResetRate, "reset-rate", 0, "Percentage of connections reset without a response")
+		fs.Float64Var(&rule.TruncateRate, "truncate-rate", 0, "Percentage of response bodies cut short")
+		fs.IntVar(&rule.TruncateBytes, "truncate-bytes", 0, "Bytes sent before a truncated body is cut")
+		fs.Float64Var(&rule.WSDropRate, "ws-drop-rate", 0, "Percentage of WebSocket connections dropped")
+		fs.DurationVar(&dropAfter, "ws-drop-after", 0, "How long a doomed WebSocket stays open")
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: httpsify fault list
+       httpsify fault add [options] <name>
+       httpsify fault enable|disable|remove <name>
+
+Manages fault injection rules on a running httpsify.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var faults []proxy.FaultStatus
+	switch command {
+	case "list":
+		if err := dashboard.call(http.MethodGet, "/api/faults", nil, &faults); err != nil {
+			return err
+		}
+	case "add", "enable", "disable", "remove":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return errors.New("expected exactly one fault name")
+		}
+		name := fs.Arg(0)
+		var err error
+		switch command {
+		case "add":
+			rule.Name, rule.Methods = name, config.ParseList(methods)
+			rule.LatencyMS, rule.JitterMS = int(latency.Milliseconds()), int(jitter.Milliseconds())
+			rule.WSDropAfterMS = int(dropAfter.Milliseconds())
+			err = dashboard.call(http.MethodPost, "/api/faults", rule, &faults)
+		case "remove":
+			err = dashboard.call(http.MethodDelete, "/api/faults/"+url.PathEscape(name), nil, &faults)
+		default:
+			err = dashboard.call(http.MethodPost, "/api/faults/"+url.PathEscape(name)+"/"+command, nil, &faults)
+		}
+		if err != nil {
+			return err
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown fault command %q", command)
+	}
+
+	if len(faults) == 0 {
+		fmt.Fprintf(os.Stderr, "%sNo fault rules.%s\n", colorDim, colorReset)
+	}
+	for _, f := range faults {
+		state := colorGreen + "on " + colorReset
+		if f.Disabled {
+			state = colorDim + "off" + colorReset
+		}
+		fmt.Printf("%s  %s%-20s%s %s  %s(%d injected)%s\n", state, colorBold, f.Name, colorReset, f.Summary(), colorDim, f.Injected, colorReset)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -36,6 +37,17 @@ func main() {
 }
 
 func run() error {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "share":
+			return runShare(os.Args[2:])
+		case "replay":
+			return runReplay(os.Args[2:])
+		case "fault":
+			return runFault(os.Args[2:])
+		}
+	}
+
 	cfg := config.DefaultConfig()
 	if err := parseFlags(cfg); err != nil {
 		return err
@@ -52,6 +64,7 @@ func run() error {
 		CertPath:   cfg.CertPath,
 		KeyPath:    cfg.KeyPath,
 		SelfSigned: cfg.SelfSigned,
+		Domains:    cfg.DomainSuffixes(),
 	})
 	if err != nil {
 		return fmt.Errorf("TLS configuration error: %w", err)
@@ -67,33 +80,94 @@ func run() error {
 		WriteTimeout:      time.Duration(cfg.WriteTimeout) * time.Second,
 	}
 
-	errChan := make(chan error, 1)
+	p := proxy.NewServer(cfg, logger)
+	server.Handler = p
+	token, err := p.DashboardToken()
+	if err != nil {
+		return fmt.Errorf("dashboard token error: %w", err)
+	}
+	servers := []*http.Server{server}
+
+	errChan := make(chan error, 3)
+	if cfg.HTTPEnabled {
+		httpServer := &http.Server{
+			Addr:              cfg.HTTPListenAddr,
+			Handler:           p.HTTPHandler(),
+			ReadHeaderTimeout: server.ReadHeaderTimeout,
+			IdleTimeout:       server.IdleTimeout,
+			WriteTimeout:      server.WriteTimeout,
+		}
+		servers = append(servers, httpServer)
+
+		go func() {
+			if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				errChan <- err
+			}
+		}()
+	}
+
+	if cfg.TCPListenAddr != "" {
+		tcpListener, err := net.Listen("tcp", cfg.TCPListenAddr)
+		if err != nil {
+			return fmt.Errorf("tcp listener error: %w", err)
+		}
+		defer tcpListener.Close()
+
+		go func() {
+			if err := p.ServeTCP(tcpListener, tlsCfg); err != nil {
+				errChan <- err
+			}
+		}()
+	}
+
 	go func() {
-		p := proxy.NewServer(cfg, logger)
-		server.Handler = p
-		
-		printStartupBox(cfg.ListenAddr, p.GetListeningPorts())
+		printStartupBox(cfg, p.GetListeningPorts(), token)
 		if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 			errChan <- err
 		}
 	}()
 
-	return waitForShutdown(server, logger, errChan)
+	return waitForShutdown(servers, logger, errChan)
 }
 
 func parseFlags(cfg *config.Config) error {
 	var (
 		listen     = flag.String("listen", cfg.ListenAddr, "Listen address (e.g., :443)")
+		httpOn     = flag.Bool("http", cfg.HTTPEnabled, "Also listen for plain HTTP and redirect to HTTPS")
+		httpListen = flag.String("http-listen", cfg.HTTPListenAddr, "Plain HTTP listen address used with --http")
+		tcpListen  = flag.String("tcp-listen", "", "Listen address for SNI-routed TLS over TCP (e.g., :6443)")
+		tcpPass    = flag.String("tcp-passthrough", "", "Comma-separated ports/ranges whose TLS is passed through untouched on --tcp-listen")
 		certPath   = flag.String("cert", cfg.CertPath, "Path to TLS certificate (PEM)")
 		keyPath    = flag.String("key", cfg.KeyPath, "Path to TLS private key (PEM)")
+		stateDir   = flag.String("state-dir", "", "Directory for the dashboard token and share link key (default ./.httpsify)")
 		selfSigned = flag.Bool("self-signed", true, "Generate self-signed certificate if missing (enabled by default)")
 		denyPorts  = flag.String("deny-ports", strings.Join(config.DefaultDenyPorts, ","), "Comma-separated list of denied ports/ranges")
 		allowRange = flag.String("allow-range", fmt.Sprintf("%d-%d", cfg.AllowRange.Start, cfg.AllowRange.End), "Allowed port range")
+		clients    = flag.String("allow-clients", "", "Comma-separated client IPs, CIDRs or lan allowed besides loopback")
+		httpACL    = flag.String("http-allow-clients", "", "Client allowlist for the plain HTTP listener (default: --allow-clients)")
+		tcpACL     = flag.String("tcp-allow-clients", "", "Client allowlist for the TCP listener (default: --allow-clients)")
+		allowHosts = flag.String("allow-hosts", "", "Comma-separated upstream hosts, wildcards or CIDRs allowed besides loopback")
+		suffixes   = flag.String("suffixes", strings.Join(cfg.Suffixes, ","), "Comma-separated domain suffixes (e.g., localhost,test)")
+		hostTmpl   = flag.String("host-template", cfg.HostTemplate, "Hostname template for port routes ({port}, {name}, {suffix})")
+		configFile = flag.String("config", "", "Path to JSON config file with route definitions")
+		routes     = flag.String("routes", "", "Comma-separated named routes (e.g., billing=8000,app/api=8000,api=172.17.0.3:8080,app=spa:./dist)")
+		defRoute   = flag.String("default-route", "", "Route used for unknown names (default: 404)")
+		cors       = flag.String("cors", "", "Comma-separated origins allowed by the default CORS policy (e.g., *.localhost)")
+		holdTime   = flag.Int("hold-timeout", cfg.HoldTimeout, "Seconds to hold requests while a backend restarts (0 disables)")
+		network    = flag.String("network", "", "Comma-separated network profiles per route or client (e.g., api=3g,192.168.1.50=flaky-wifi)")
+		mirror     = flag.String("mirror", "", "Comma-separated shadow ports that get a copy of each request to a route (e.g., api=8001)")
+		mirrorDiff = flag.Bool("mirror-diff", false, "Record differences between primary and shadow responses")
+		rewrite    = flag.Bool("rewrite-body", cfg.RewriteBody, "Rewrite upstream URLs in HTML, JS and CSS responses")
+		inspect    = flag.Int("inspect", cfg.InspectSize, "Number of recent exchanges kept by the traffic inspector (0 disables)")
+		inspBody   = flag.Int("inspect-body", cfg.InspectBodyLimit, "Bytes of each request and response body kept by the inspector")
 		verbose    = flag.Bool("verbose", cfg.Verbose, "Enable verbose/debug logging")
 		accessLog  = flag.Bool("access-log", cfg.AccessLog, "Enable access logging")
 		showVer    = flag.Bool("version", false, "Show version information")
 	)
 
+	var lan lanFlag
+	flag.Var(&lan, "lan", "Serve LAN clients; optionally limited to routes, ports or dashboard (e.g., --lan=shop,3000)")
+
 	setupFlagUsage()
 	flag.Parse()
 
@@ -103,8 +177,30 @@ func parseFlags(cfg *config.Config) error {
 	}
 
 	cfg.LoadFromEnv()
+	if *configFile == "" {
+		*configFile = os.Getenv("HTTPSIFY_CONFIG")
+	}
+	if *configFile != "" {
+		if err := cfg.LoadFile(*configFile); err != nil {
+			return err
+		}
+	}
 	cfg.ListenAddr, cfg.CertPath, cfg.KeyPath = *listen, *certPath, *keyPath
+	if *stateDir != "" {
+		cfg.StateDir = *stateDir
+	}
 	cfg.SelfSigned, cfg.Verbose, cfg.AccessLog = *selfSigned, *verbose, *accessLog
+	if *tcpListen != "" {
+		cfg.TCPListenAddr = *tcpListen
+	}
+
+	if *tcpPass != "" {
+		ranges, err := config.ParsePortRanges(*tcpPass)
+		if err != nil {
+			return fmt.Errorf("invalid tcp-passthrough: %w", err)
+		}
+		cfg.TCPPassthrough = ranges
+	}
 
 	if *denyPorts != "" {
 		ranges, err := config.ParsePortRanges(*denyPorts)
@@ -122,14 +218,116 @@ func parseFlags(cfg *config.Config) error {
 		cfg.AllowRange = pr
 	}
 
+	if *allowHosts != "" {
+		cfg.AllowHosts = config.ParseList(*allowHosts)
+	}
+
+	if *clients != "" {
+		cfg.AllowClients = config.ParseList(*clients)
+	}
+	if *httpACL != "" {
+		cfg.HTTPAllowClients = config.ParseList(*httpACL)
+	}
+	if *tcpACL != "" {
+		cfg.TCPAllowClients = config.ParseList(*tcpACL)
+	}
+	if lan.set {
+		cfg.LAN = lan.entries
+	}
+
+	if *routes != "" {
+		parsed, err := config.ParseRoutes(*routes)
+		if err != nil {
+			return fmt.Errorf("invalid routes: %w", err)
+		}
+		cfg.AddRoutes(parsed)
+	}
+
+	if *defRoute != "" {
+		cfg.DefaultRoute = *defRoute
+	}
+
+	if *cors != "" {
+		cfg.CORS = &config.CORSPolicy{AllowOrigins: config.ParseList(*cors)}
+	}
+
+	if *network != "" {
+		rules, err := config.ParseNetworkRules(*network)
+		if err != nil {
+			return fmt.Errorf("invalid network: %w", err)
+		}
+		cfg.Network = append(cfg.Network, rules...)
+	}
+
+	if *mirror != "" {
+		mirrors, err := config.ParseMirrors(*mirror)
+		if err != nil {
+			return fmt.Errorf("invalid mirror: %w", err)
+		}
+		cfg.Mirrors = append(cfg.Mirrors, mirrors...)
+	}
+	if *mirrorDiff {
+		for i := range cfg.Mirrors {
+			cfg.Mirrors[i].Diff = true
+		}
+	}
+
+	var flagErr error
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "suffixes":
+			parsed, err := config.ParseSuffixes(*suffixes)
+			if err != nil {
+				flagErr = fmt.Errorf("invalid suffixes: %w", err)
+			} else if len(parsed) > 0 {
+				cfg.Suffixes = parsed
+			}
+		case "host-template":
+			cfg.HostTemplate = *hostTmpl
+		case "http":
+			cfg.HTTPEnabled = *httpOn
+		case "http-listen":
+			cfg.HTTPListenAddr = *httpListen
+		case "rewrite-body":
+			cfg.RewriteBody = *rewrite
+		case "inspect":
+			cfg.InspectSize = *inspect
+		case "inspect-body":
+			cfg.InspectBodyLimit = *inspBody
+		case "hold-timeout":
+			cfg.HoldTimeout = *holdTime
+		}
+	})
+
+	return flagErr
+}
+
+type lanFlag struct {
+	set     bool
+	entries []string
+}
+
+func (f *lanFlag) String() string {
+	return strings.Join(f.entries, ",")
+}
+
+func (f *lanFlag) Set(v string) error {
+	f.set, f.entries = true, config.ParseLAN(v)
 	return nil
 }
 
+func (f *lanFlag) IsBoolFlag() bool {
+	return true
+}
+
 func setupFlagUsage() {
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, `httpsify - Dynamic HTTPS reverse proxy for local development
 
 Usage: httpsify [options]
+       httpsify share [--ttl 24h] <route>
+       httpsify replay [--route r] [--method m] [--path p] [-H 'Name: value'] [--data body] <request-id>
+       httpsify fault list|add|enable|disable|remove [options] [name]
 
 Routes requests based on subdomain:
   https://<port>.localhost  ->  http://127.0.0.1:<port>
@@ -138,17 +336,45 @@ Examples:
   https://8000.localhost  ->  http://127.0.0.1:8000
   https://3000.localhost  ->  http://127.0.0.1:3000
 
+Named routes (--routes billing=8000):
+  https://billing.localhost  ->  http://127.0.0.1:8000
+
+Directory routes (--routes docs=dir:./site,app=spa:./dist):
+  https://app.localhost      ->  files in ./dist, unknown paths serve index.html
+
 Options:
 `)
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, `
 Environment Variables:
   HTTPSIFY_LISTEN       Listen address
+  HTTPSIFY_HTTP         Enable plain HTTP redirect listener (true/false)
+  HTTPSIFY_HTTP_LISTEN  Plain HTTP listen address
+  HTTPSIFY_TCP_LISTEN   SNI-routed TCP listen address
+  HTTPSIFY_TCP_PASSTHROUGH  TLS passthrough ports list
   HTTPSIFY_CERT         Certificate path
   HTTPSIFY_KEY          Key path
+  HTTPSIFY_STATE_DIR    Directory for generated secrets and the dashboard token
   HTTPSIFY_SELF_SIGNED  Generate self-signed cert (true/false)
   HTTPSIFY_DENY_PORTS   Denied ports list
   HTTPSIFY_ALLOW_RANGE  Allowed port range
+  HTTPSIFY_CONFIG       Config file path
+  HTTPSIFY_ROUTES       Named routes list
+  HTTPSIFY_ALLOW_CLIENTS  Client IPs/CIDRs allowed besides loopback
+  HTTPSIFY_HTTP_ALLOW_CLIENTS  Client allowlist for the HTTP listener
+  HTTPSIFY_TCP_ALLOW_CLIENTS   Client allowlist for the TCP listener
+  HTTPSIFY_LAN          Open routes to LAN clients (true or route list)
+  HTTPSIFY_ALLOW_HOSTS  Allowed non-loopback upstream hosts
+  HTTPSIFY_SUFFIXES     Domain suffixes list
+  HTTPSIFY_HOST_TEMPLATE  Hostname template for port routes
+  HTTPSIFY_DEFAULT_ROUTE  Fallback route for unknown names
+  HTTPSIFY_CORS         Origins allowed by the default CORS policy
+  HTTPSIFY_REWRITE_BODY  Rewrite upstream URLs in response bodies (true/false)
+  HTTPSIFY_INSPECT      Exchanges kept by the traffic inspector (0 disables)
+  HTTPSIFY_INSPECT_BODY  Body bytes kept per captured request/response
+  HTTPSIFY_HOLD_TIMEOUT  Seconds to hold requests while a backend restarts
+  HTTPSIFY_NETWORK      Network profiles per route or client (e.g., api=3g)
+  HTTPSIFY_MIRROR       Shadow ports per route (e.g., api=8001)
   HTTPSIFY_VERBOSE      Verbose logging (true/false)
   HTTPSIFY_ACCESS_LOG   Access logging (true/false)
 
@@ -156,7 +382,8 @@ Environment Variables:
 	}
 }
 
-func printStartupBox(listenAddr string, services []proxy.ServiceInfo) {
+func printStartupBox(cfg *config.Config, services []proxy.ServiceInfo, token string) {
+	listenAddr := cfg.ListenAddr
 	ips := netutil.GetLocalIPs()
 	
 	var webServices []proxy.ServiceInfo
@@ -174,14 +401,39 @@ func printStartupBox(listenAddr string, services []proxy.ServiceInfo) {
 	fmt.Fprintf(os.Stderr, "  %s────────────────────────────────────────────────────────────%s\n", colorDim, colorReset)
 	
 	fmt.Fprintf(os.Stderr, "  %sReady%s    %sServer is up and listening%s\n", colorGreen, colorReset, colorDim, colorReset)
-	fmt.Fprintf(os.Stderr, "  %sLocal%s    %shttps://localhost%s%s\n", colorBold, colorReset, colorCyan, listenAddr, colorReset)
+	fmt.Fprintf(os.Stderr, "  %sLocal%s    %shttps://%s%s%s\n", colorBold, colorReset, colorCyan, cfg.RootHost(), listenAddr, colorReset)
+	fmt.Fprintf(os.Stderr, "  %sToken%s    %s%s%s\n", colorBold, colorReset, colorYellow, token, colorReset)
 	
-	for _, ip := range ips {
-		fmt.Fprintf(os.Stderr, "  %sNetwork%s  %shttps://%s%s%s\n", colorBold, colorReset, colorCyan, ip, listenAddr, colorReset)
+	if cfg.HTTPEnabled {
+		fmt.Fprintf(os.Stderr, "  %sHTTP%s     %shttp://%s%s%s %s→ redirects to HTTPS%s\n", colorBold, colorReset, colorCyan, cfg.RootHost(), cfg.HTTPListenAddr, colorReset, colorDim, colorReset)
+	}
+
+	if cfg.TCPListenAddr != "" {
+		fmt.Fprintf(os.Stderr, "  %sTCP%s      %stls://*.%s%s%s %s→ routed by SNI%s\n", colorBold, colorReset, colorCyan, cfg.PrimarySuffix(), cfg.TCPListenAddr, colorReset, colorDim, colorReset)
+	}
+
+	if len(cfg.LAN) == 0 && len(cfg.AllowClients) == 0 {
+		fmt.Fprintf(os.Stderr, "  %sNetwork%s  %sloopback clients only (use --lan to expose)%s\n", colorBold, colorReset, colorDim, colorReset)
+	} else {
+		for _, ip := range ips {
+			fmt.Fprintf(os.Stderr, "  %sNetwork%s  %shttps://%s%s%s\n", colorBold, colorReset, colorCyan, ip, listenAddr, colorReset)
+		}
+		if len(cfg.LAN) > 0 {
+			fmt.Fprintf(os.Stderr, "  %sLAN%s      %s%s%s\n", colorBold, colorReset, colorYellow, strings.Join(cfg.LAN, ", "), colorReset)
+		}
 	}
 	
 	fmt.Fprintf(os.Stderr, "  %s────────────────────────────────────────────────────────────%s\n\n", colorDim, colorReset)
 	
+	// Named Routes
+	if len(cfg.Routes) > 0 {
+		fmt.Fprintf(os.Stderr, "  %sNamed Routes:%s\n", colorBold, colorReset)
+		for _, rt := range cfg.Routes {
+			fmt.Fprintf(os.Stderr, "    %shttps://%s%s%s  %s→%s  %s\n", colorCyan, cfg.RouteHost(rt.Name), rt.Path, colorReset, colorDim, colorReset, rt.Address())
+		}
+		fmt.Fprintf(os.Stderr, "\n")
+	}
+
 	// Web Services
 	if len(webServices) > 0 {
 		fmt.Fprintf(os.Stderr, "  %sProxy Ready Services:%s\n", colorBold, colorReset)
@@ -192,7 +444,7 @@ func printStartupBox(listenAddr string, services []proxy.ServiceInfo) {
 			}
 			proc := svc.ProcessName
 			if proc == "" { proc = "unknown" }
-			fmt.Fprintf(os.Stderr, "    %shttps://%d.localhost%s  %s→%s  %s\n", colorCyan, svc.Port, colorReset, colorDim, colorReset, proc)
+			fmt.Fprintf(os.Stderr, "    %shttps://%s%s  %s→%s  %s\n", colorCyan, cfg.PortHost(svc.Port, svc.ProcessName), colorReset, colorDim, colorReset, proc)
 		}
 		fmt.Fprintf(os.Stderr, "\n")
 	}
@@ -217,13 +469,13 @@ func printStartupBox(listenAddr string, services []proxy.ServiceInfo) {
 	}
 	
 	fmt.Fprintf(os.Stderr, "  %sDashboard Control Center%s\n", colorBold, colorReset)
-	fmt.Fprintf(os.Stderr, "  %sOpen %shttps://localhost%s%s to manage all services and see details.\n", colorDim, colorCyan, colorReset, colorDim)
+	fmt.Fprintf(os.Stderr, "  %sOpen %shttps://%s%s%s to manage all services and see details.\n", colorDim, colorCyan, cfg.RootHost(), colorReset, colorDim)
 	
 	fmt.Fprintf(os.Stderr, "\n  %s%s[Ctrl+C]%s %sto stop the server%s\n", colorBold, colorYellow, colorReset, colorDim, colorReset)
 	fmt.Fprintf(os.Stderr, "\n")
 }
 
-func waitForShutdown(server *http.Server, logger *logging.Logger, errChan <-chan error) error {
+func waitForShutdown(servers []*http.Server, logger *logging.Logger, errChan <-chan error) error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
@@ -237,8 +489,14 @@ func waitForShutdown(server *http.Server, logger *logging.Logger, errChan <-chan
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
-		return fmt.Errorf("shutdown error: %w", err)
+	var shutdownErr error
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil && shutdownErr == nil {
+			shutdownErr = fmt.Errorf("shutdown error: %w", err)
+		}
+	}
+	if shutdownErr != nil {
+		return shutdownErr
 	}
 
 	logger.Info("server stopped")
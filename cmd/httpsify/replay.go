@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/imcanugur/httpsify/internal/proxy"
+)
+
+type headerFlag map[string]string
+
+func (h headerFlag) String() string {
+	return ""
+}
+
+func (h headerFlag) Set(v string) error {
+	name, value, ok := strings.Cut(v, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected Name: value, got %q", v)
+	}
+	h[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	return nil
+}
+
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	dashboard := addDashboardFlags(fs)
+	route := fs.String("route", "", "Send to a different route or port")
+	method := fs.String("method", "", "Override the request method")
+	path := fs.String("path", "", "Override the path and query")
+	data := fs.String("data", "", "Override the body; @file reads it from a file")
+	headers := headerFlag{}
+	fs.Var(headers, "H", "Set a header (Name: value); an empty value removes it. Repeatable")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: httpsify replay [options] <request-id>\n\nReplays a captured exchange through a running httpsify.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one request ID")
+	}
+
+	edit := proxy.ReplayRequest{Route: *route, Method: *method, Path: *path}
+	if len(headers) > 0 {
+		edit.Headers = headers
+	}
+	isSet := false
+	fs.Visit(func(f *flag.Flag) { isSet = isSet || f.Name == "data" })
+	if isSet {
+		body := *data
+		if file, ok := strings.CutPrefix(body, "@"); ok {
+			b, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read body: %w", err)
+			}
+			body = string(b)
+		}
+		edit.Body = &body
+	}
+
+	var result proxy.ReplayResult
+	if err := dashboard.call(http.MethodPost, "/api/exchanges/"+url.PathEscape(fs.Arg(0))+"/replay", edit, &result); err != nil {
+		return fmt.Errorf("replay failed: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "%sReplayed%s %s %s→%s %s %sstatus %d%s\n", colorGreen, colorReset, result.ReplayOf, colorDim, colorReset, result.ID, colorBold, result.Status, colorReset)
+	if result.Body.Binary {
+		fmt.Fprintf(os.Stderr, "%s(binary body, %d bytes)%s\n", colorDim, result.Body.Size, colorReset)
+		return nil
+	}
+	fmt.Println(result.Body.Text)
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+	"github.com/imcanugur/httpsify/internal/proxy"
+)
+
+func runShare(args []string) error {
+	fs := flag.NewFlagSet("share", flag.ExitOnError)
+	configFile := fs.String("config", os.Getenv("HTTPSIFY_CONFIG"), "Path to JSON config file with route definitions")
+	stateDir := fs.String("state-dir", "", "Directory holding the share link key (default ./.httpsify)")
+	ttl := fs.Duration("ttl", 24*time.Hour, "How long the link and its session stay valid")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: httpsify share [options] <route>\n\nPrints a signed, expiring link for a route with share_links enabled.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one route name")
+	}
+	if *ttl <= 0 {
+		return errors.New("ttl must be positive")
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.LoadFromEnv()
+	if *configFile != "" {
+		if err := cfg.LoadFile(*configFile); err != nil {
+			return err
+		}
+	}
+	if *stateDir != "" {
+		cfg.StateDir = *stateDir
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	link, err := proxy.NewServer(cfg, nil).ShareURL(fs.Arg(0), *ttl)
+	if err != nil {
+		return err
+	}
+	fmt.Println(link)
+	return nil
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const passwordHashPrefix = "sha256:"
+
+type RouteAuth struct {
+	Users      map[string]string `json:"users,omitempty"`
+	Tokens     []string          `json:"tokens,omitempty"`
+	ShareLinks bool              `json:"share_links,omitempty"`
+}
+
+func (a *RouteAuth) Validate() error {
+	if a == nil {
+		return nil
+	}
+	if len(a.Users) == 0 && len(a.Tokens) == 0 && !a.ShareLinks {
+		return errors.New("auth: set users, tokens or share_links")
+	}
+	for user, password := range a.Users {
+		if user == "" || strings.Contains(user, ":") {
+			return fmt.Errorf("auth: invalid user name %q", user)
+		}
+		if password == "" {
+			return fmt.Errorf("auth: user %q has an empty password", user)
+		}
+		if hash, ok := strings.CutPrefix(password, passwordHashPrefix); ok {
+			if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+				return fmt.Errorf("auth: user %q has an invalid sha256 password hash", user)
+			}
+		}
+	}
+	for _, token := range a.Tokens {
+		if token == "" {
+			return errors.New("auth: tokens must not be empty")
+		}
+	}
+	return nil
+}
+
+func (a *RouteAuth) CheckPassword(user, password string) bool {
+	want, ok := a.Users[user]
+	if !ok {
+		return false
+	}
+	if hash, ok := strings.CutPrefix(want, passwordHashPrefix); ok {
+		sum := sha256.Sum256([]byte(password))
+		return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(hash))) == 1
+	}
+	return subtle.ConstantTimeCompare([]byte(password), []byte(want)) == 1
+}
+
+func (a *RouteAuth) CheckToken(token string) bool {
+	ok := false
+	for _, t := range a.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+func (a *RouteAuth) UsesAuthorization() bool {
+	return len(a.Users) > 0 || len(a.Tokens) > 0
+}
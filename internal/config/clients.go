@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	ListenerHTTPS = "https"
+	ListenerHTTP  = "http"
+	ListenerTCP   = "tcp"
+
+	DashboardName = "dashboard"
+)
+
+type ClientACL struct {
+	Nets []*net.IPNet
+	LAN  bool
+}
+
+func ParseClientACL(list []string) (ClientACL, error) {
+	var acl ClientACL
+	for _, entry := range list {
+		entry = strings.TrimSpace(entry)
+		if strings.EqualFold(entry, "lan") {
+			acl.LAN = true
+			continue
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			acl.Nets = append(acl.Nets, cidr)
+			continue
+		}
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return ClientACL{}, fmt.Errorf("invalid client address %q: use an IP, a CIDR or lan", entry)
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		acl.Nets = append(acl.Nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return acl, nil
+}
+
+func (a ClientACL) Allows(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	return ip.IsLoopback() || a.Contains(ip)
+}
+
+func (a ClientACL) Contains(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if a.LAN && IsLANAddress(ip) {
+		return true
+	}
+	for _, n := range a.Nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func IsLANAddress(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLinkLocalUnicast()
+}
+
+func ParseLAN(s string) []string {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "false", "0":
+		return nil
+	case "true", "1":
+		return []string{"*"}
+	}
+	return ParseList(strings.ToLower(s))
+}
+
+func (c *Config) ListenerClients(listener string) []string {
+	switch {
+	case listener == ListenerHTTP && len(c.HTTPAllowClients) > 0:
+		return c.HTTPAllowClients
+	case listener == ListenerTCP && len(c.TCPAllowClients) > 0:
+		return c.TCPAllowClients
+	}
+	return c.AllowClients
+}
+
+func (c *Config) LANAllows(name string, port int) bool {
+	for _, entry := range c.LAN {
+		if entry == "*" || (name != "" && entry == name) || (port != 0 && entry == strconv.Itoa(port)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) validateClients() error {
+	lists := [][]string{c.AllowClients, c.HTTPAllowClients, c.TCPAllowClients}
+	for _, rt := range c.Routes {
+		lists = append(lists, rt.AllowClients)
+	}
+	for _, list := range lists {
+		if _, err := ParseClientACL(list); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
@@ -20,13 +22,45 @@ var DefaultDenyPorts = []string{
 type Config struct {
 	ListenAddr string
 
+	HTTPEnabled    bool
+	HTTPListenAddr string
+
+	TCPListenAddr  string
+	TCPPassthrough []PortRange
+
 	CertPath string
 	KeyPath  string
+	StateDir string
 
 	SelfSigned bool
 
 	DenyPorts  []PortRange
 	AllowRange PortRange
+	AllowHosts []string
+
+	AllowClients     []string
+	HTTPAllowClients []string
+	TCPAllowClients  []string
+	LAN              []string
+
+	Routes       []Route
+	DefaultRoute string
+
+	Suffixes     []string
+	HostTemplate string
+
+	RewriteBody bool
+	CORS        *CORSPolicy
+
+	InspectSize      int
+	InspectBodyLimit int
+
+	Faults []FaultRule
+
+	Network         []NetworkRule
+	NetworkProfiles []NetworkProfile
+
+	Mirrors []MirrorRule
 
 	Verbose   bool
 	AccessLog bool
@@ -35,6 +69,7 @@ type Config struct {
 	IdleTimeout       int
 	WriteTimeout      int
 	DialTimeout       int
+	HoldTimeout       int
 }
 
 type PortRange struct {
@@ -56,10 +91,16 @@ func (pr PortRange) String() string {
 func DefaultConfig() *Config {
 	cfg := &Config{
 		ListenAddr:        ":443",
+		HTTPListenAddr:    ":80",
 		CertPath:          "./cert/localhost.pem",
 		KeyPath:           "./cert/localhost-key.pem",
+		StateDir:          "./.httpsify",
 		SelfSigned:        true,
 		AllowRange:        PortRange{Start: 1024, End: 65535},
+		Suffixes:          append([]string(nil), DefaultSuffixes...),
+		HostTemplate:      DefaultHostTemplate,
+		InspectSize:       100,
+		InspectBodyLimit:  64 * 1024,
 		Verbose:           false,
 		AccessLog:         true,
 		ReadHeaderTimeout: 10,
@@ -77,12 +118,29 @@ func (c *Config) LoadFromEnv() {
 	if v := os.Getenv("HTTPSIFY_LISTEN"); v != "" {
 		c.ListenAddr = v
 	}
+	if v := os.Getenv("HTTPSIFY_HTTP"); v != "" {
+		c.HTTPEnabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("HTTPSIFY_HTTP_LISTEN"); v != "" {
+		c.HTTPListenAddr = v
+	}
+	if v := os.Getenv("HTTPSIFY_TCP_LISTEN"); v != "" {
+		c.TCPListenAddr = v
+	}
+	if v := os.Getenv("HTTPSIFY_TCP_PASSTHROUGH"); v != "" {
+		if ranges, err := ParsePortRanges(v); err == nil {
+			c.TCPPassthrough = ranges
+		}
+	}
 	if v := os.Getenv("HTTPSIFY_CERT"); v != "" {
 		c.CertPath = v
 	}
 	if v := os.Getenv("HTTPSIFY_KEY"); v != "" {
 		c.KeyPath = v
 	}
+	if v := os.Getenv("HTTPSIFY_STATE_DIR"); v != "" {
+		c.StateDir = v
+	}
 	if v := os.Getenv("HTTPSIFY_SELF_SIGNED"); v != "" {
 		c.SelfSigned = v == "true" || v == "1"
 	}
@@ -96,6 +154,68 @@ func (c *Config) LoadFromEnv() {
 			c.AllowRange = ranges[0]
 		}
 	}
+	if v := os.Getenv("HTTPSIFY_ALLOW_HOSTS"); v != "" {
+		c.AllowHosts = ParseList(v)
+	}
+	if v := os.Getenv("HTTPSIFY_ALLOW_CLIENTS"); v != "" {
+		c.AllowClients = ParseList(v)
+	}
+	if v := os.Getenv("HTTPSIFY_HTTP_ALLOW_CLIENTS"); v != "" {
+		c.HTTPAllowClients = ParseList(v)
+	}
+	if v := os.Getenv("HTTPSIFY_TCP_ALLOW_CLIENTS"); v != "" {
+		c.TCPAllowClients = ParseList(v)
+	}
+	if v := os.Getenv("HTTPSIFY_LAN"); v != "" {
+		c.LAN = ParseLAN(v)
+	}
+	if v := os.Getenv("HTTPSIFY_SUFFIXES"); v != "" {
+		if suffixes, err := ParseSuffixes(v); err == nil && len(suffixes) > 0 {
+			c.Suffixes = suffixes
+		}
+	}
+	if v := os.Getenv("HTTPSIFY_HOST_TEMPLATE"); v != "" {
+		c.HostTemplate = v
+	}
+	if v := os.Getenv("HTTPSIFY_ROUTES"); v != "" {
+		if routes, err := ParseRoutes(v); err == nil {
+			c.AddRoutes(routes)
+		}
+	}
+	if v := os.Getenv("HTTPSIFY_DEFAULT_ROUTE"); v != "" {
+		c.DefaultRoute = v
+	}
+	if v := os.Getenv("HTTPSIFY_CORS"); v != "" {
+		c.CORS = &CORSPolicy{AllowOrigins: ParseList(v)}
+	}
+	if v := os.Getenv("HTTPSIFY_REWRITE_BODY"); v != "" {
+		c.RewriteBody = v == "true" || v == "1"
+	}
+	if v := os.Getenv("HTTPSIFY_INSPECT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.InspectSize = n
+		}
+	}
+	if v := os.Getenv("HTTPSIFY_INSPECT_BODY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.InspectBodyLimit = n
+		}
+	}
+	if v := os.Getenv("HTTPSIFY_HOLD_TIMEOUT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.HoldTimeout = n
+		}
+	}
+	if v := os.Getenv("HTTPSIFY_NETWORK"); v != "" {
+		if rules, err := ParseNetworkRules(v); err == nil {
+			c.Network = append(c.Network, rules...)
+		}
+	}
+	if v := os.Getenv("HTTPSIFY_MIRROR"); v != "" {
+		if mirrors, err := ParseMirrors(v); err == nil {
+			c.Mirrors = append(c.Mirrors, mirrors...)
+		}
+	}
 	if v := os.Getenv("HTTPSIFY_VERBOSE"); v != "" {
 		c.Verbose = v == "true" || v == "1"
 	}
@@ -104,6 +224,70 @@ func (c *Config) LoadFromEnv() {
 	}
 }
 
+type fileConfig struct {
+	DefaultRoute string      `json:"default_route"`
+	AllowHosts   []string    `json:"allow_hosts"`
+	Suffixes     []string    `json:"suffixes"`
+	HostTemplate string      `json:"host_template"`
+	HoldTimeout  int         `json:"hold_timeout"`
+	CORS         *CORSPolicy `json:"cors"`
+	Routes       []Route     `json:"routes"`
+	Faults       []FaultRule `json:"faults"`
+
+	Network         []NetworkRule    `json:"network"`
+	NetworkProfiles []NetworkProfile `json:"network_profiles"`
+	Mirrors         []MirrorRule     `json:"mirrors"`
+
+	AllowClients     []string `json:"allow_clients"`
+	HTTPAllowClients []string `json:"http_allow_clients"`
+	TCPAllowClients  []string `json:"tcp_allow_clients"`
+	LAN              []string `json:"lan"`
+}
+
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var fc fileConfig
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	c.AddRoutes(fc.Routes)
+	c.Faults = append(c.Faults, fc.Faults...)
+	c.Network = append(c.Network, fc.Network...)
+	c.NetworkProfiles = append(c.NetworkProfiles, fc.NetworkProfiles...)
+	c.Mirrors = append(c.Mirrors, fc.Mirrors...)
+	c.AllowHosts = append(c.AllowHosts, fc.AllowHosts...)
+	if len(fc.Suffixes) > 0 {
+		suffixes, err := ParseSuffixes(strings.Join(fc.Suffixes, ","))
+		if err != nil {
+			return err
+		}
+		c.Suffixes = suffixes
+	}
+	if fc.HostTemplate != "" {
+		c.HostTemplate = fc.HostTemplate
+	}
+	if fc.DefaultRoute != "" {
+		c.DefaultRoute = fc.DefaultRoute
+	}
+	if fc.HoldTimeout != 0 {
+		c.HoldTimeout = fc.HoldTimeout
+	}
+	if fc.CORS != nil {
+		c.CORS = fc.CORS
+	}
+	c.AllowClients = append(c.AllowClients, fc.AllowClients...)
+	c.HTTPAllowClients = append(c.HTTPAllowClients, fc.HTTPAllowClients...)
+	c.TCPAllowClients = append(c.TCPAllowClients, fc.TCPAllowClients...)
+	c.LAN = append(c.LAN, fc.LAN...)
+
+	return nil
+}
+
 func ParsePortRanges(s string) ([]PortRange, error) {
 	if s == "" {
 		return nil, nil
@@ -183,10 +367,111 @@ func (c *Config) IsPortAllowed(port int) bool {
 	return c.AllowRange.Contains(port)
 }
 
+func (c *Config) IsTCPPassthrough(port int) bool {
+	for _, pr := range c.TCPPassthrough {
+		if pr.Contains(port) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) ListenPort() int {
+	return addrPort(c.ListenAddr, 443)
+}
+
+func (c *Config) HTTPListenPort() int {
+	return addrPort(c.HTTPListenAddr, 80)
+}
+
+func (c *Config) TCPListenPort() int {
+	return addrPort(c.TCPListenAddr, 0)
+}
+
+func (c *Config) IsListenPort(port int) bool {
+	if port == c.ListenPort() {
+		return true
+	}
+	if c.HTTPEnabled && port == c.HTTPListenPort() {
+		return true
+	}
+	return c.TCPListenAddr != "" && port == c.TCPListenPort()
+}
+
+func addrPort(addr string, fallback int) int {
+	_, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fallback
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fallback
+	}
+	return port
+}
+
+func (c *Config) IsUpstreamAllowed(host string, port int) bool {
+	return c.IsHostAllowed(host) && c.IsPortAllowed(port)
+}
+
+func (c *Config) IsHostAllowed(host string) bool {
+	if IsLoopbackHost(host) {
+		return true
+	}
+
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	ip := net.ParseIP(host)
+	for _, entry := range c.AllowHosts {
+		entry = strings.ToLower(entry)
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if strings.HasPrefix(entry, "*.") {
+			if strings.HasSuffix(host, entry[1:]) {
+				return true
+			}
+			continue
+		}
+		if entry == host {
+			return true
+		}
+	}
+
+	return false
+}
+
+func IsLoopbackHost(host string) bool {
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	if host == "" || host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func ParseList(s string) []string {
+	var items []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			items = append(items, part)
+		}
+	}
+	return items
+}
+
 func (c *Config) Validate() error {
 	if c.ListenAddr == "" {
 		return errors.New("listen address is required")
 	}
+	if c.HTTPEnabled && c.HTTPListenAddr == "" {
+		return errors.New("http listen address is required when the http listener is enabled")
+	}
+	if len(c.TCPPassthrough) > 0 && c.TCPListenAddr == "" {
+		return errors.New("tcp passthrough ports require a tcp listen address")
+	}
 
 	if !c.SelfSigned {
 		if c.CertPath == "" || c.KeyPath == "" {
@@ -206,6 +491,45 @@ func (c *Config) Validate() error {
 	if c.DialTimeout < 1 {
 		return errors.New("dial timeout must be at least 1 second")
 	}
+	if c.HoldTimeout < 0 || (c.HoldTimeout > 0 && c.HoldTimeout >= c.WriteTimeout) {
+		return fmt.Errorf("hold timeout must be between 0 and the %ds write timeout", c.WriteTimeout)
+	}
+	if c.InspectSize < 0 || c.InspectBodyLimit < 0 {
+		return errors.New("inspector size and body limit must not be negative")
+	}
+
+	for _, suffix := range c.Suffixes {
+		if !suffixPattern.MatchString(suffix) {
+			return fmt.Errorf("invalid domain suffix %q", suffix)
+		}
+	}
+	if err := ValidateHostTemplate(c.Template()); err != nil {
+		return err
+	}
+
+	if err := c.CORS.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.validateClients(); err != nil {
+		return err
+	}
+
+	if err := c.validateRoutes(); err != nil {
+		return err
+	}
+
+	if err := validateFaults(c.Faults); err != nil {
+		return err
+	}
+
+	if err := c.validateNetwork(); err != nil {
+		return err
+	}
+
+	if err := c.validateMirrors(); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -1,6 +1,9 @@
 package config
 
 import (
+	"net"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -285,3 +288,574 @@ func TestValidatePort(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRoutes(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Route
+		wantErr bool
+	}{
+		{
+			name:  "empty",
+			input: "",
+			want:  nil,
+		},
+		{
+			name:  "single route",
+			input: "billing=8000",
+			want:  []Route{{Name: "billing", Port: 8000}},
+		},
+		{
+			name:  "multiple routes with spaces",
+			input: " billing = 8000 , Shop=3000 ",
+			want: []Route{
+				{Name: "billing", Port: 8000},
+				{Name: "shop", Port: 3000},
+			},
+		},
+		{
+			name:  "path prefix",
+			input: "app=3000,app/api=8000",
+			want: []Route{
+				{Name: "app", Port: 3000},
+				{Name: "app", Path: "/api", Port: 8000},
+			},
+		},
+		{
+			name:  "upstream host",
+			input: "api=172.17.0.3:8080,v6=[::1]:9000",
+			want: []Route{
+				{Name: "api", Host: "172.17.0.3", Port: 8080},
+				{Name: "v6", Host: "::1", Port: 9000},
+			},
+		},
+		{
+			name:  "unix socket",
+			input: "app=unix:/run/app.sock",
+			want:  []Route{{Name: "app", Socket: "/run/app.sock"}},
+		},
+		{
+			name:    "relative unix socket",
+			input:   "app=unix:app.sock",
+			wantErr: true,
+		},
+		{
+			name:  "https upstream",
+			input: "spring=https://localhost:8443,kestrel=https+insecure://localhost:5001",
+			want: []Route{
+				{Name: "spring", Host: "localhost", Port: 8443, Scheme: "https"},
+				{Name: "kestrel", Host: "localhost", Port: 5001, Scheme: "https", TLS: &UpstreamTLS{Insecure: true}},
+			},
+		},
+		{
+			name:  "h2c upstream",
+			input: "grpc=h2c://localhost:50051",
+			want: []Route{
+				{Name: "grpc", Host: "localhost", Port: 50051, Scheme: "h2c"},
+			},
+		},
+		{
+			name:  "static directory",
+			input: "docs=dir:.,app=spa:.",
+			want: []Route{
+				{Name: "docs", Dir: "."},
+				{Name: "app", Dir: ".", SPA: true},
+			},
+		},
+		{
+			name:    "missing directory",
+			input:   "docs=dir:./does-not-exist",
+			wantErr: true,
+		},
+		{
+			name:    "static file",
+			input:   "docs=dir:config.go",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported scheme",
+			input:   "app=ftp://localhost:21",
+			wantErr: true,
+		},
+		{
+			name:    "missing port",
+			input:   "billing",
+			wantErr: true,
+		},
+		{
+			name:    "numeric name",
+			input:   "8000=3000",
+			wantErr: true,
+		},
+		{
+			name:    "invalid name",
+			input:   "bad_name=3000",
+			wantErr: true,
+		},
+		{
+			name:    "invalid port",
+			input:   "billing=70000",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRoutes(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRoutes() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRoutes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "httpsify.json")
+	data := `{"default_route": "shop", "routes": [{"name": "billing", "port": 8000}, {"name": "shop", "port": 3000}]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.AddRoutes([]Route{{Name: "billing", Port: 9000}})
+	if err := cfg.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	if routes := cfg.FindRoutes("billing"); len(routes) != 1 || routes[0].Port != 8000 {
+		t.Errorf("FindRoutes(billing) = %v, want a single route on port 8000", routes)
+	}
+	if cfg.DefaultRoute != "shop" {
+		t.Errorf("DefaultRoute = %q, want shop", cfg.DefaultRoute)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	cfg.DefaultRoute = "missing"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() accepted unknown default route")
+	}
+}
+
+func TestConfigIsUpstreamAllowed(t *testing.T) {
+	cfg := &Config{
+		DenyPorts:  []PortRange{{Start: 22, End: 22}},
+		AllowRange: PortRange{Start: 1024, End: 65535},
+		AllowHosts: []string{"172.17.0.0/16", "devbox.lan", "*.internal"},
+	}
+
+	tests := []struct {
+		name string
+		host string
+		port int
+		want bool
+	}{
+		{"loopback default", "", 8000, true},
+		{"loopback ip", "127.0.0.1", 8000, true},
+		{"loopback v6", "::1", 8000, true},
+		{"localhost", "localhost", 8000, true},
+		{"cidr match", "172.17.0.3", 8080, true},
+		{"cidr miss", "172.18.0.3", 8080, false},
+		{"exact host", "devbox.lan", 3000, true},
+		{"exact host case", "DevBox.lan", 3000, true},
+		{"wildcard host", "api.corp.internal", 3000, true},
+		{"wildcard bare suffix", "internal", 3000, false},
+		{"unknown host", "example.com", 3000, false},
+		{"allowed host denied port", "172.17.0.3", 22, false},
+		{"allowed host outside range", "172.17.0.3", 80, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.IsUpstreamAllowed(tt.host, tt.port); got != tt.want {
+				t.Errorf("Config.IsUpstreamAllowed(%q, %d) = %v, want %v", tt.host, tt.port, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSuffixes(t *testing.T) {
+	got, err := ParseSuffixes("localhost, *.test, .dev.corp.internal")
+	if err != nil {
+		t.Fatalf("ParseSuffixes() error = %v", err)
+	}
+	want := []string{"localhost", "test", "dev.corp.internal"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSuffixes() = %v, want %v", got, want)
+	}
+
+	if _, err := ParseSuffixes("bad_suffix"); err == nil {
+		t.Error("ParseSuffixes() accepted invalid suffix")
+	}
+}
+
+func TestValidateHostTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl    string
+		wantErr bool
+	}{
+		{"{port}.{suffix}", false},
+		{"{name}-{port}.{suffix}", false},
+		{"p{port}.{suffix}", false},
+		{"{name}.{suffix}", true},
+		{"{port}.localhost", true},
+		{"{port}-{port}.{suffix}", true},
+		{"{host}-{port}.{suffix}", true},
+		{"{port}.{name}.{suffix}", true},
+		{"app.{port}.{suffix}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			err := ValidateHostTemplate(tt.tmpl)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateHostTemplate(%q) error = %v, wantErr %v", tt.tmpl, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPortHost(t *testing.T) {
+	cfg := DefaultConfig()
+	if got := cfg.PortHost(3000, "node"); got != "3000.localhost" {
+		t.Errorf("PortHost() = %q, want 3000.localhost", got)
+	}
+
+	cfg.Suffixes = []string{"test"}
+	cfg.HostTemplate = "{name}-{port}.{suffix}"
+	if got := cfg.PortHost(3000, "My App"); got != "my-app-3000.test" {
+		t.Errorf("PortHost() = %q, want my-app-3000.test", got)
+	}
+	if got := cfg.PortHost(3000, ""); got != "app-3000.test" {
+		t.Errorf("PortHost() = %q, want app-3000.test", got)
+	}
+	if got := cfg.RouteHost("billing"); got != "billing.test" {
+		t.Errorf("RouteHost() = %q, want billing.test", got)
+	}
+}
+
+func TestHeaderRulesValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   *HeaderRules
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"templated", &HeaderRules{Request: HeaderOps{Set: map[string]string{"X-Real-IP": "{client_ip}"}}}, false},
+		{"wildcard remove", &HeaderRules{Response: HeaderOps{Remove: []string{"X-Debug-*"}}}, false},
+		{"unknown placeholder", &HeaderRules{Request: HeaderOps{Set: map[string]string{"X-Foo": "{user}"}}}, true},
+		{"invalid name", &HeaderRules{Response: HeaderOps{Append: map[string]string{"Bad Header": "x"}}}, true},
+		{"bare wildcard", &HeaderRules{Response: HeaderOps{Remove: []string{"*"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rules.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCORSPolicyAllowsOrigin(t *testing.T) {
+	p := &CORSPolicy{AllowOrigins: []string{"*.localhost", "https://app.test", "http://127.0.0.1:5173"}}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://3000.localhost", true},
+		{"https://3000.localhost:8443", true},
+		{"http://web.localhost", true},
+		{"https://localhost", false},
+		{"https://evil-localhost", false},
+		{"https://app.test", true},
+		{"http://app.test", false},
+		{"http://127.0.0.1:5173", true},
+		{"http://127.0.0.1:5174", false},
+		{"null", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			if got := p.AllowsOrigin(tt.origin); got != tt.want {
+				t.Errorf("AllowsOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+
+	for _, pattern := range []string{"", "https://*", "foo*.localhost", "*.*.localhost"} {
+		if err := (&CORSPolicy{AllowOrigins: []string{pattern}}).Validate(); err == nil {
+			t.Errorf("Validate() accepted origin pattern %q", pattern)
+		}
+	}
+	if err := (&CORSPolicy{AllowOrigins: []string{"*"}, AllowCredentials: true}).Validate(); err == nil {
+		t.Error("Validate() accepted credentials for any origin")
+	}
+}
+
+func TestClientACL(t *testing.T) {
+	acl, err := ParseClientACL([]string{"10.1.0.0/16", "203.0.113.7", "lan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.4.2", true},
+		{"203.0.113.7", true},
+		{"203.0.113.8", false},
+		{"192.168.1.20", true},
+		{"fe80::1", true},
+		{"8.8.8.8", false},
+	}
+
+	for _, tt := range tests {
+		if got := acl.Allows(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("Allows(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+
+	if (ClientACL{}).Allows(net.ParseIP("192.168.1.20")) {
+		t.Error("empty ACL should only allow loopback")
+	}
+	if _, err := ParseClientACL([]string{"my-laptop"}); err == nil {
+		t.Error("ParseClientACL() accepted a hostname")
+	}
+}
+
+func TestLANAllows(t *testing.T) {
+	tests := []struct {
+		lan  string
+		name string
+		port int
+		want bool
+	}{
+		{"true", "shop", 3000, true},
+		{"false", "shop", 3000, false},
+		{"shop,8000", "shop", 3000, true},
+		{"shop,8000", "", 8000, true},
+		{"shop,8000", "", 3000, false},
+		{"dashboard", DashboardName, 0, true},
+	}
+
+	for _, tt := range tests {
+		c := &Config{LAN: ParseLAN(tt.lan)}
+		if got := c.LANAllows(tt.name, tt.port); got != tt.want {
+			t.Errorf("LAN %q: LANAllows(%q, %d) = %v, want %v", tt.lan, tt.name, tt.port, got, tt.want)
+		}
+	}
+}
+
+func TestRouteAuth(t *testing.T) {
+	hash := "sha256:2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"
+	a := &RouteAuth{
+		Users:  map[string]string{"plain": "secret", "hashed": hash},
+		Tokens: []string{"tok"},
+	}
+	if err := a.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		user, password string
+		want           bool
+	}{
+		{"plain", "secret", true},
+		{"plain", "Secret", false},
+		{"hashed", "secret", true},
+		{"hashed", hash, false},
+		{"nobody", "secret", false},
+	}
+	for _, tt := range tests {
+		if got := a.CheckPassword(tt.user, tt.password); got != tt.want {
+			t.Errorf("CheckPassword(%q, %q) = %v, want %v", tt.user, tt.password, got, tt.want)
+		}
+	}
+	if !a.CheckToken("tok") || a.CheckToken("to") {
+		t.Error("CheckToken mismatch")
+	}
+
+	for _, bad := range []*RouteAuth{
+		{},
+		{Users: map[string]string{"a:b": "x"}},
+		{Users: map[string]string{"a": ""}},
+		{Users: map[string]string{"a": "sha256:xyz"}},
+		{Tokens: []string{""}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestFaultRule(t *testing.T) {
+	rule := &FaultRule{Name: "slow", Route: "api", Methods: []string{"post"}, Path: "/orders/*", LatencyMS: 100}
+	if err := rule.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		route, method, path string
+		want                bool
+	}{
+		{"api", "POST", "/orders/1", true},
+		{"API", "post", "/orders/1/items", true},
+		{"api", "GET", "/orders/1", false},
+		{"shop", "POST", "/orders/1", false},
+		{"api", "POST", "/users", false},
+	}
+	for _, tt := range tests {
+		if got := rule.Matches(tt.route, tt.method, tt.path); got != tt.want {
+			t.Errorf("Matches(%q, %q, %q) = %v, want %v", tt.route, tt.method, tt.path, got, tt.want)
+		}
+	}
+	if all := (&FaultRule{Name: "all", ErrorRate: 5}); !all.Matches("3000", "GET", "/") {
+		t.Error("rule without filters should match every request")
+	}
+
+	for _, bad := range []FaultRule{
+		{Name: "Bad Name", LatencyMS: 1},
+		{Name: "noop"},
+		{Name: "rate", ErrorRate: 101},
+		{Name: "status", ErrorRate: 10, ErrorStatus: 404},
+		{Name: "path", Path: "api/*", LatencyMS: 1},
+		{Name: "pattern", Path: "/[", LatencyMS: 1},
+		{Name: "negative", LatencyMS: -1},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want error", bad)
+		}
+	}
+	if err := validateFaults([]FaultRule{{Name: "a", LatencyMS: 1}, {Name: "a", JitterMS: 1}}); err == nil {
+		t.Error("duplicate fault names accepted")
+	}
+}
+
+func TestParseNetworkRules(t *testing.T) {
+	rules, err := ParseNetworkRules("api=3G, 192.168.1.50=flaky-wifi,10.0.0.0/8=edge,lan=4g,*=slow-3g")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []NetworkRule{
+		{Route: "api", Profile: "3g"},
+		{Clients: []string{"192.168.1.50"}, Profile: "flaky-wifi"},
+		{Clients: []string{"10.0.0.0/8"}, Profile: "edge"},
+		{Clients: []string{"lan"}, Profile: "4g"},
+		{Profile: "slow-3g"},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("ParseNetworkRules = %+v, want %+v", rules, want)
+	}
+	for _, bad := range []string{"api", "=3g", "api="} {
+		if _, err := ParseNetworkRules(bad); err == nil {
+			t.Errorf("ParseNetworkRules(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestValidateNetwork(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles []NetworkProfile
+		rules    []NetworkRule
+		wantErr  bool
+	}{
+		{"preset", nil, []NetworkRule{{Route: "api", Profile: "3g"}}, false},
+		{"custom", []NetworkProfile{{Name: "hotel", DownKbps: 500, StallRate: 10, StallMS: 2000}}, []NetworkRule{{Profile: "hotel"}}, false},
+		{"unknown profile", nil, []NetworkRule{{Profile: "5g"}}, true},
+		{"bad client", nil, []NetworkRule{{Clients: []string{"phone"}, Profile: "3g"}}, true},
+		{"stall without duration", []NetworkProfile{{Name: "x", StallRate: 5}}, nil, true},
+		{"negative", []NetworkProfile{{Name: "x", RTTMS: -1}}, nil, true},
+		{"duplicate", []NetworkProfile{{Name: "x"}, {Name: "x"}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.NetworkProfiles, cfg.Network = tt.profiles, tt.rules
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	p, _ := DefaultConfig().LookupNetworkProfile("flaky-wifi")
+	if got := p.Summary(); got != "5 Mbps down, 2 Mbps up, 80ms RTT, 5% stalls of 1.5s" {
+		t.Errorf("Summary() = %q", got)
+	}
+}
+
+func TestHoldTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		global  int
+		route   int
+		wantErr bool
+	}{
+		{"disabled", 0, 0, false},
+		{"global", 10, 0, false},
+		{"route", 0, 5, false},
+		{"negative", -1, 0, true},
+		{"negative route", 0, -1, true},
+		{"beyond write timeout", 30, 0, true},
+		{"route beyond write timeout", 0, 45, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.HoldTimeout = tt.global
+			cfg.Routes = []Route{{Name: "api", Port: 8000, HoldTimeout: tt.route}}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMirrors(t *testing.T) {
+	rules, err := ParseMirrors("API=8001, 3000=3001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MirrorRule{{Route: "api", Port: 8001}, {Route: "3000", Port: 3001}}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("ParseMirrors = %+v, want %+v", rules, want)
+	}
+	for _, bad := range []string{"api", "=8001", "api=", "api=port"} {
+		if _, err := ParseMirrors(bad); err == nil {
+			t.Errorf("ParseMirrors(%q) succeeded, want error", bad)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mirrors []MirrorRule
+		wantErr bool
+	}{
+		{"valid", []MirrorRule{{Route: "api", Port: 8001, Diff: true}}, false},
+		{"missing route", []MirrorRule{{Port: 8001}}, true},
+		{"bad port", []MirrorRule{{Route: "api", Port: 70000}}, true},
+		{"denied port", []MirrorRule{{Route: "api", Port: 22}}, true},
+		{"same port", []MirrorRule{{Route: "3000", Port: 3000}}, true},
+		{"duplicate", []MirrorRule{{Route: "api", Port: 8001}, {Route: "api", Port: 8002}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Mirrors = tt.mirrors
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var DefaultCORSMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+
+type CORSPolicy struct {
+	AllowOrigins     []string `json:"allow_origins"`
+	AllowCredentials bool     `json:"allow_credentials,omitempty"`
+	AllowMethods     []string `json:"allow_methods,omitempty"`
+	AllowHeaders     []string `json:"allow_headers,omitempty"`
+	ExposeHeaders    []string `json:"expose_headers,omitempty"`
+	MaxAge           int      `json:"max_age,omitempty"`
+	ForwardPreflight bool     `json:"forward_preflight,omitempty"`
+}
+
+func (p *CORSPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if len(p.AllowOrigins) == 0 {
+		return errors.New("cors: allow_origins must not be empty")
+	}
+	for _, origin := range p.AllowOrigins {
+		host := origin
+		if _, rest, ok := strings.Cut(origin, "://"); ok {
+			host = rest
+		}
+		if host == "" || (origin != "*" && strings.Contains(strings.TrimPrefix(host, "*."), "*")) {
+			return fmt.Errorf("cors: invalid origin pattern %q", origin)
+		}
+	}
+	if p.AllowCredentials && p.AllowsAnyOrigin() {
+		return errors.New(`cors: allow_credentials cannot be combined with the "*" origin; list the allowed origins instead`)
+	}
+	if p.MaxAge < 0 {
+		return errors.New("cors: max_age must not be negative")
+	}
+	return nil
+}
+
+func (p *CORSPolicy) AllowsAnyOrigin() bool {
+	for _, pattern := range p.AllowOrigins {
+		if pattern == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *CORSPolicy) AllowsOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return false
+	}
+
+	for _, pattern := range p.AllowOrigins {
+		if pattern == "*" {
+			return true
+		}
+		if matchOrigin(strings.ToLower(pattern), strings.ToLower(u.Scheme), strings.ToLower(u.Host)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchOrigin(pattern, scheme, host string) bool {
+	if patternScheme, rest, ok := strings.Cut(pattern, "://"); ok {
+		if patternScheme != scheme {
+			return false
+		}
+		pattern = rest
+	}
+
+	hostname := host
+	if i := strings.LastIndex(host, ":"); i > strings.LastIndex(host, "]") {
+		hostname = host[:i]
+	}
+
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		target := hostname
+		if strings.Contains(suffix, ":") {
+			target = host
+		}
+		return strings.HasSuffix(target, "."+suffix)
+	}
+	if strings.Contains(pattern, ":") {
+		return pattern == host
+	}
+	return pattern == hostname
+}
+
+func (p *CORSPolicy) Methods() []string {
+	if len(p.AllowMethods) == 0 {
+		return DefaultCORSMethods
+	}
+	return p.AllowMethods
+}
+
+func (p *CORSPolicy) AllowsMethod(method string) bool {
+	for _, m := range p.Methods() {
+		if m == "*" || strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *CORSPolicy) DisallowedHeader(headers []string) string {
+	if len(p.AllowHeaders) == 0 {
+		return ""
+	}
+	for _, h := range headers {
+		allowed := false
+		for _, a := range p.AllowHeaders {
+			if a == "*" || strings.EqualFold(a, h) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return h
+		}
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+type FaultRule struct {
+	Name     string   `json:"name"`
+	Route    string   `json:"route,omitempty"`
+	Methods  []string `json:"methods,omitempty"`
+	Path     string   `json:"path,omitempty"`
+	Disabled bool     `json:"disabled,omitempty"`
+
+	LatencyMS     int     `json:"latency_ms,omitempty"`
+	JitterMS      int     `json:"jitter_ms,omitempty"`
+	ErrorRate     float64 `json:"error_rate,omitempty"`
+	ErrorStatus   int     `json:"error_status,omitempty"`
+	ResetRate     float64 `json:"reset_rate,omitempty"`
+	TruncateRate  float64 `json:"truncate_rate,omitempty"`
+	TruncateBytes int     `json:"truncate_bytes,omitempty"`
+	WSDropRate    float64 `json:"ws_drop_rate,omitempty"`
+	WSDropAfterMS int     `json:"ws_drop_after_ms,omitempty"`
+}
+
+func (f *FaultRule) Validate() error {
+	if !routeNamePattern.MatchString(f.Name) {
+		return fmt.Errorf("fault %q: use lowercase letters, digits and dashes for the name", f.Name)
+	}
+	if f.Path != "" {
+		if !strings.HasPrefix(f.Path, "/") {
+			return fmt.Errorf("fault %q: path %q must start with /", f.Name, f.Path)
+		}
+		if _, err := path.Match(f.Path, "/"); err != nil {
+			return fmt.Errorf("fault %q: invalid path pattern %q", f.Name, f.Path)
+		}
+	}
+	if f.LatencyMS < 0 || f.JitterMS < 0 || f.TruncateBytes < 0 || f.WSDropAfterMS < 0 {
+		return fmt.Errorf("fault %q: latency, jitter, truncate_bytes and ws_drop_after_ms must not be negative", f.Name)
+	}
+	for _, rate := range []float64{f.ErrorRate, f.ResetRate, f.TruncateRate, f.WSDropRate} {
+		if rate < 0 || rate > 100 {
+			return fmt.Errorf("fault %q: rates are percentages between 0 and 100", f.Name)
+		}
+	}
+	if f.ErrorStatus != 0 && (f.ErrorStatus < 500 || f.ErrorStatus > 599) {
+		return fmt.Errorf("fault %q: error_status must be a 5xx code", f.Name)
+	}
+	if f.LatencyMS == 0 && f.JitterMS == 0 && f.ErrorRate == 0 && f.ResetRate == 0 && f.TruncateRate == 0 && f.WSDropRate == 0 {
+		return fmt.Errorf("fault %q: set at least one of latency_ms, jitter_ms or a rate", f.Name)
+	}
+	return nil
+}
+
+func (f *FaultRule) Matches(route, method, p string) bool {
+	if f.Route != "" && !strings.EqualFold(f.Route, route) {
+		return false
+	}
+	if len(f.Methods) > 0 {
+		found := false
+		for _, m := range f.Methods {
+			found = found || strings.EqualFold(m, method)
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Path == "" {
+		return true
+	}
+	if ok, _ := path.Match(f.Path, p); ok {
+		return true
+	}
+	// path.Match stops "*" at "/"; a trailing "*" also covers deeper paths.
+	prefix, ok := strings.CutSuffix(f.Path, "*")
+	return ok && strings.HasPrefix(p, prefix)
+}
+
+func (f *FaultRule) Summary() string {
+	var parts []string
+	target := f.Route
+	if target == "" {
+		target = "all routes"
+	}
+	if len(f.Methods) > 0 {
+		target = strings.Join(f.Methods, "|") + " " + target
+	}
+	parts = append(parts, target+f.Path)
+	if f.LatencyMS > 0 {
+		parts = append(parts, fmt.Sprintf("+%dms", f.LatencyMS))
+	}
+	if f.JitterMS > 0 {
+		parts = append(parts, fmt.Sprintf("jitter %dms", f.JitterMS))
+	}
+	for _, rate := range []struct {
+		label string
+		value float64
+	}{{"5xx", f.ErrorRate}, {"reset", f.ResetRate}, {"truncate", f.TruncateRate}, {"ws drop", f.WSDropRate}} {
+		if rate.value > 0 {
+			parts = append(parts, fmt.Sprintf("%s %g%%", rate.label, rate.value))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func validateFaults(faults []FaultRule) error {
+	seen := make(map[string]bool)
+	for i := range faults {
+		if err := faults[i].Validate(); err != nil {
+			return err
+		}
+		if seen[faults[i].Name] {
+			return errors.New("duplicate fault " + faults[i].Name)
+		}
+		seen[faults[i].Name] = true
+	}
+	return nil
+}
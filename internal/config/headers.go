@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+var HeaderTemplateVars = []string{"{client_ip}", "{request_id}", "{route}", "{host}", "{method}", "{upstream}"}
+
+type HeaderRules struct {
+	Request  HeaderOps `json:"request,omitempty"`
+	Response HeaderOps `json:"response,omitempty"`
+}
+
+type HeaderOps struct {
+	Set    map[string]string `json:"set,omitempty"`
+	Append map[string]string `json:"append,omitempty"`
+	Remove []string          `json:"remove,omitempty"`
+}
+
+func (h *HeaderRules) Validate() error {
+	if h == nil {
+		return nil
+	}
+	for _, ops := range []HeaderOps{h.Request, h.Response} {
+		if err := ops.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o HeaderOps) validate() error {
+	for _, values := range []map[string]string{o.Set, o.Append} {
+		for name, value := range values {
+			if err := validateHeaderName(name); err != nil {
+				return err
+			}
+			for _, p := range placeholderPattern.FindAllString(value, -1) {
+				if !isHeaderTemplateVar(p) {
+					return fmt.Errorf("header %s: unknown placeholder %s", name, p)
+				}
+			}
+		}
+	}
+	for _, name := range o.Remove {
+		if err := validateHeaderName(strings.TrimSuffix(name, "*")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateHeaderName(name string) error {
+	if name == "" || strings.ContainsAny(name, " :\t\r\n") {
+		return fmt.Errorf("invalid header name %q", name)
+	}
+	return nil
+}
+
+func isHeaderTemplateVar(p string) bool {
+	for _, v := range HeaderTemplateVars {
+		if p == v {
+			return true
+		}
+	}
+	return false
+}
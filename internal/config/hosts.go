@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const DefaultHostTemplate = "{port}.{suffix}"
+
+var DefaultSuffixes = []string{"localhost", "localtest.me"}
+
+var (
+	suffixPattern      = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+	placeholderPattern = regexp.MustCompile(`\{[^}]*\}`)
+	nameCleanPattern   = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+func ParseSuffixes(s string) ([]string, error) {
+	var suffixes []string
+	for _, item := range ParseList(s) {
+		suffix := NormalizeSuffix(item)
+		if !suffixPattern.MatchString(suffix) {
+			return nil, fmt.Errorf("invalid domain suffix %q", item)
+		}
+		suffixes = append(suffixes, suffix)
+	}
+	return suffixes, nil
+}
+
+func NormalizeSuffix(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "*")
+	return strings.Trim(s, ".")
+}
+
+func ValidateHostTemplate(tmpl string) error {
+	if !strings.Contains(tmpl, "{port}") {
+		return fmt.Errorf("host template %q must contain {port}", tmpl)
+	}
+	if !strings.HasSuffix(tmpl, ".{suffix}") {
+		return fmt.Errorf("host template %q must end with .{suffix}", tmpl)
+	}
+	for _, p := range placeholderPattern.FindAllString(tmpl, -1) {
+		if p != "{port}" && p != "{name}" && p != "{suffix}" {
+			return fmt.Errorf("host template %q: unknown placeholder %s", tmpl, p)
+		}
+	}
+	if strings.Count(tmpl, "{port}") > 1 || strings.Count(tmpl, "{name}") > 1 {
+		return fmt.Errorf("host template %q: placeholders may appear only once", tmpl)
+	}
+	if strings.Contains(strings.TrimSuffix(tmpl, ".{suffix}"), ".") {
+		return fmt.Errorf("host template %q: use a single label before .{suffix}; the *.suffix certificate does not cover deeper names", tmpl)
+	}
+	return nil
+}
+
+func (c *Config) DomainSuffixes() []string {
+	if len(c.Suffixes) == 0 {
+		return DefaultSuffixes
+	}
+	return c.Suffixes
+}
+
+func (c *Config) PrimarySuffix() string {
+	return c.DomainSuffixes()[0]
+}
+
+func (c *Config) Template() string {
+	if c.HostTemplate == "" {
+		return DefaultHostTemplate
+	}
+	return c.HostTemplate
+}
+
+func (c *Config) PortHost(port int, name string) string {
+	name = strings.Trim(nameCleanPattern.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if name == "" {
+		name = "app"
+	}
+	return strings.NewReplacer(
+		"{port}", strconv.Itoa(port),
+		"{name}", name,
+		"{suffix}", c.PrimarySuffix(),
+	).Replace(c.Template())
+}
+
+func (c *Config) RouteHost(name string) string {
+	return name + "." + c.PrimarySuffix()
+}
+
+func (c *Config) RootHost() string {
+	return c.PrimarySuffix()
+}
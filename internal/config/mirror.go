@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type MirrorRule struct {
+	Route string `json:"route"`
+	Port  int    `json:"port"`
+	Diff  bool   `json:"diff,omitempty"`
+}
+
+func ParseMirrors(s string) ([]MirrorRule, error) {
+	var rules []MirrorRule
+	for _, part := range ParseList(s) {
+		route, portStr, ok := strings.Cut(part, "=")
+		route = strings.TrimSpace(route)
+		port, err := strconv.Atoi(strings.TrimSpace(portStr))
+		if !ok || route == "" || err != nil {
+			return nil, fmt.Errorf("invalid mirror %q: use route=port (e.g., api=8001)", part)
+		}
+		rules = append(rules, MirrorRule{Route: strings.ToLower(route), Port: port})
+	}
+	return rules, nil
+}
+
+func (c *Config) validateMirrors() error {
+	seen := make(map[string]bool)
+	for _, m := range c.Mirrors {
+		if m.Route == "" {
+			return fmt.Errorf("mirror to port %d: route is required", m.Port)
+		}
+		if err := ValidatePort(m.Port); err != nil {
+			return fmt.Errorf("mirror for %s: %w", m.Route, err)
+		}
+		if !c.IsPortAllowed(m.Port) || c.IsListenPort(m.Port) {
+			return fmt.Errorf("mirror for %s: port %d is denied or used by httpsify itself", m.Route, m.Port)
+		}
+		if port, err := strconv.Atoi(m.Route); err == nil && port == m.Port {
+			return fmt.Errorf("mirror for %s: shadow port must differ from the route's port", m.Route)
+		}
+		if seen[m.Route] {
+			return fmt.Errorf("duplicate mirror for %s", m.Route)
+		}
+		seen[m.Route] = true
+	}
+	return nil
+}
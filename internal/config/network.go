@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+type NetworkProfile struct {
+	Name      string  `json:"name"`
+	DownKbps  int     `json:"down_kbps,omitempty"`
+	UpKbps    int     `json:"up_kbps,omitempty"`
+	RTTMS     int     `json:"rtt_ms,omitempty"`
+	StallRate float64 `json:"stall_rate,omitempty"`
+	StallMS   int     `json:"stall_ms,omitempty"`
+}
+
+var NetworkPresets = []NetworkProfile{
+	{Name: "slow-3g", DownKbps: 400, UpKbps: 400, RTTMS: 2000},
+	{Name: "3g", DownKbps: 1600, UpKbps: 750, RTTMS: 560},
+	{Name: "4g", DownKbps: 9000, UpKbps: 9000, RTTMS: 170},
+	{Name: "edge", DownKbps: 240, UpKbps: 200, RTTMS: 840},
+	{Name: "flaky-wifi", DownKbps: 5000, UpKbps: 2000, RTTMS: 80, StallRate: 5, StallMS: 1500},
+}
+
+func (p *NetworkProfile) Validate() error {
+	if !routeNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("network profile %q: use lowercase letters, digits and dashes for the name", p.Name)
+	}
+	if p.DownKbps < 0 || p.UpKbps < 0 || p.RTTMS < 0 || p.StallMS < 0 {
+		return fmt.Errorf("network profile %q: bandwidth, rtt_ms and stall_ms must not be negative", p.Name)
+	}
+	if p.StallRate < 0 || p.StallRate > 100 {
+		return fmt.Errorf("network profile %q: stall_rate is a percentage between 0 and 100", p.Name)
+	}
+	if p.StallRate > 0 && p.StallMS == 0 {
+		return fmt.Errorf("network profile %q: stall_rate requires stall_ms", p.Name)
+	}
+	return nil
+}
+
+func (p *NetworkProfile) Summary() string {
+	var parts []string
+	if p.DownKbps > 0 {
+		parts = append(parts, formatKbps(p.DownKbps)+" down")
+	}
+	if p.UpKbps > 0 {
+		parts = append(parts, formatKbps(p.UpKbps)+" up")
+	}
+	if p.RTTMS > 0 {
+		parts = append(parts, fmt.Sprintf("%dms RTT", p.RTTMS))
+	}
+	if p.StallRate > 0 {
+		parts = append(parts, fmt.Sprintf("%g%% stalls of %s", p.StallRate, time.Duration(p.StallMS)*time.Millisecond))
+	}
+	if len(parts) == 0 {
+		return "unthrottled"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatKbps(kbps int) string {
+	if kbps >= 1000 {
+		return fmt.Sprintf("%g Mbps", float64(kbps)/1000)
+	}
+	return fmt.Sprintf("%d kbps", kbps)
+}
+
+type NetworkRule struct {
+	Route   string   `json:"route,omitempty"`
+	Clients []string `json:"clients,omitempty"`
+	Profile string   `json:"profile"`
+}
+
+func (r NetworkRule) Target() string {
+	var parts []string
+	if r.Route != "" {
+		parts = append(parts, r.Route)
+	}
+	if len(r.Clients) > 0 {
+		parts = append(parts, strings.Join(r.Clients, ", "))
+	}
+	if len(parts) == 0 {
+		return "all traffic"
+	}
+	return strings.Join(parts, " from ")
+}
+
+func ParseNetworkRules(s string) ([]NetworkRule, error) {
+	var rules []NetworkRule
+	for _, part := range ParseList(s) {
+		target, profile, ok := strings.Cut(part, "=")
+		target, profile = strings.TrimSpace(target), strings.TrimSpace(profile)
+		if !ok || target == "" || profile == "" {
+			return nil, fmt.Errorf("invalid network rule %q: use target=profile (e.g., api=3g)", part)
+		}
+		rule := NetworkRule{Profile: strings.ToLower(profile)}
+		switch {
+		case target == "*":
+		case strings.EqualFold(target, "lan") || net.ParseIP(target) != nil || strings.Contains(target, "/"):
+			rule.Clients = []string{target}
+		default:
+			rule.Route = target
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (c *Config) LookupNetworkProfile(name string) (NetworkProfile, bool) {
+	for _, p := range c.NetworkProfiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	for _, p := range NetworkPresets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return NetworkProfile{}, false
+}
+
+func (c *Config) validateNetwork() error {
+	seen := make(map[string]bool)
+	for i := range c.NetworkProfiles {
+		if err := c.NetworkProfiles[i].Validate(); err != nil {
+			return err
+		}
+		if seen[c.NetworkProfiles[i].Name] {
+			return fmt.Errorf("duplicate network profile %s", c.NetworkProfiles[i].Name)
+		}
+		seen[c.NetworkProfiles[i].Name] = true
+	}
+	for _, rule := range c.Network {
+		if _, ok := c.LookupNetworkProfile(rule.Profile); !ok {
+			var names []string
+			for _, p := range NetworkPresets {
+				names = append(names, p.Name)
+			}
+			return fmt.Errorf("network rule for %s: unknown profile %q (presets: %s)", rule.Target(), rule.Profile, strings.Join(names, ", "))
+		}
+		if _, err := ParseClientACL(rule.Clients); err != nil {
+			return fmt.Errorf("network rule for %s: %w", rule.Target(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var routeNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+type Route struct {
+	Name        string `json:"name"`
+	Path        string `json:"path,omitempty"`
+	StripPrefix bool   `json:"strip_prefix,omitempty"`
+	Host        string `json:"host,omitempty"`
+	Port        int    `json:"port,omitempty"`
+	Socket      string `json:"socket,omitempty"`
+	Dir         string `json:"dir,omitempty"`
+	SPA         bool   `json:"spa,omitempty"`
+	Listing     bool   `json:"listing,omitempty"`
+	Scheme      string `json:"scheme,omitempty"`
+	AllowHTTP   bool   `json:"allow_http,omitempty"`
+	RewriteBody bool   `json:"rewrite_body,omitempty"`
+	HostHeader  string `json:"host_header,omitempty"`
+	HoldTimeout int    `json:"hold_timeout,omitempty"`
+
+	AllowClients []string `json:"allow_clients,omitempty"`
+
+	TLS     *UpstreamTLS `json:"tls,omitempty"`
+	Headers *HeaderRules `json:"headers,omitempty"`
+	CORS    *CORSPolicy  `json:"cors,omitempty"`
+	Auth    *RouteAuth   `json:"auth,omitempty"`
+}
+
+type UpstreamTLS struct {
+	CA         string `json:"ca,omitempty"`
+	Insecure   bool   `json:"insecure,omitempty"`
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+	ServerName string `json:"server_name,omitempty"`
+}
+
+func (r Route) IsTLS() bool {
+	return r.Scheme == "https"
+}
+
+func (r Route) IsH2C() bool {
+	return r.Scheme == "h2c"
+}
+
+func (r Route) IsStatic() bool {
+	return r.Dir != ""
+}
+
+func (r Route) String() string {
+	return r.Name + r.Path
+}
+
+func (r Route) UpstreamHost() string {
+	if r.Host == "" {
+		return "127.0.0.1"
+	}
+	return r.Host
+}
+
+func (r Route) Address() string {
+	if r.IsStatic() {
+		if r.SPA {
+			return "spa:" + r.Dir
+		}
+		return "dir:" + r.Dir
+	}
+	addr := net.JoinHostPort(r.UpstreamHost(), strconv.Itoa(r.Port))
+	if r.Socket != "" {
+		addr = "unix:" + r.Socket
+	}
+	if r.IsTLS() || r.IsH2C() {
+		addr = r.Scheme + "://" + addr
+	}
+	return addr
+}
+
+func (r Route) IsLocal() bool {
+	return r.Socket != "" || r.IsStatic() || IsLoopbackHost(r.UpstreamHost())
+}
+
+func (r Route) Validate() error {
+	if !routeNamePattern.MatchString(r.Name) {
+		return fmt.Errorf("invalid route name %q: use lowercase letters, digits and dashes", r.Name)
+	}
+	if _, err := strconv.Atoi(r.Name); err == nil {
+		return fmt.Errorf("invalid route name %q: numeric names are reserved for ports", r.Name)
+	}
+	if r.Path != "" && !strings.HasPrefix(r.Path, "/") {
+		return fmt.Errorf("route %q: path %q must start with /", r.Name, r.Path)
+	}
+	if r.Scheme != "" && r.Scheme != "http" && r.Scheme != "https" && r.Scheme != "h2c" {
+		return fmt.Errorf("route %q: unsupported scheme %q", r.Name, r.Scheme)
+	}
+	if r.TLS != nil {
+		if !r.IsTLS() {
+			return fmt.Errorf("route %q: tls options require scheme https", r.Name)
+		}
+		if (r.TLS.ClientCert == "") != (r.TLS.ClientKey == "") {
+			return fmt.Errorf("route %q: client_cert and client_key must be set together", r.Name)
+		}
+		for _, path := range []string{r.TLS.CA, r.TLS.ClientCert, r.TLS.ClientKey} {
+			if path == "" {
+				continue
+			}
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("route %q: %w", r.Name, err)
+			}
+		}
+	}
+	if r.HostHeader != "" && r.HostHeader != "public" && r.HostHeader != "upstream" {
+		if strings.ContainsAny(r.HostHeader, "/ @?#") {
+			return fmt.Errorf("route %q: host_header must be public, upstream or a host[:port]", r.Name)
+		}
+	}
+	if r.HoldTimeout < 0 {
+		return fmt.Errorf("route %q: hold_timeout must not be negative", r.Name)
+	}
+	if err := r.Headers.Validate(); err != nil {
+		return fmt.Errorf("route %q: %w", r.Name, err)
+	}
+	if err := r.CORS.Validate(); err != nil {
+		return fmt.Errorf("route %q: %w", r.Name, err)
+	}
+	if err := r.Auth.Validate(); err != nil {
+		return fmt.Errorf("route %q: %w", r.Name, err)
+	}
+	if r.IsStatic() {
+		if r.Host != "" || r.Port != 0 || r.Socket != "" || r.Scheme != "" || r.HostHeader != "" || r.RewriteBody || r.HoldTimeout != 0 {
+			return fmt.Errorf("route %q: directory routes cannot set host, port, socket, scheme, host_header, rewrite_body or hold_timeout", r.Name)
+		}
+		info, err := os.Stat(r.Dir)
+		if err != nil {
+			return fmt.Errorf("route %q: %w", r.Name, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("route %q: %s is not a directory", r.Name, r.Dir)
+		}
+		return nil
+	}
+	if r.SPA || r.Listing {
+		return fmt.Errorf("route %q: spa and listing require a dir", r.Name)
+	}
+	if r.Socket != "" {
+		if !filepath.IsAbs(r.Socket) {
+			return fmt.Errorf("route %q: socket path %q must be absolute", r.Name, r.Socket)
+		}
+		if r.Host != "" || r.Port != 0 {
+			return fmt.Errorf("route %q: socket routes cannot set host or port", r.Name)
+		}
+		return nil
+	}
+	if err := ValidatePort(r.Port); err != nil {
+		return fmt.Errorf("route %q: %w", r.Name, err)
+	}
+	return nil
+}
+
+func ParseRoutes(s string) ([]Route, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var routes []Route
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		rt, err := ParseRoute(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route %q: %w", part, err)
+		}
+		routes = append(routes, rt)
+	}
+
+	return routes, nil
+}
+
+func ParseRoute(s string) (Route, error) {
+	key, target, ok := strings.Cut(strings.TrimSpace(s), "=")
+	if !ok {
+		return Route{}, errors.New("expected name[/path]=[host:]port, name[/path]=unix:/path or name[/path]=dir:/path")
+	}
+
+	name, path, _ := strings.Cut(strings.TrimSpace(key), "/")
+	rt := Route{Name: strings.ToLower(name)}
+	if path != "" {
+		rt.Path = "/" + path
+	}
+
+	target = strings.TrimSpace(target)
+	if scheme, rest, ok := strings.Cut(target, "://"); ok {
+		rt.Scheme, target = strings.ToLower(scheme), rest
+		if rt.Scheme == "https+insecure" {
+			rt.Scheme, rt.TLS = "https", &UpstreamTLS{Insecure: true}
+		}
+	}
+	if socket, ok := strings.CutPrefix(target, "unix:"); ok {
+		rt.Socket = socket
+		if err := rt.Validate(); err != nil {
+			return Route{}, err
+		}
+		return rt, nil
+	}
+	if kind, dir, ok := strings.Cut(target, ":"); ok && (kind == "dir" || kind == "spa") {
+		rt.Dir, rt.SPA = dir, kind == "spa"
+		if err := rt.Validate(); err != nil {
+			return Route{}, err
+		}
+		return rt, nil
+	}
+
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		host, portStr = "", target
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return Route{}, fmt.Errorf("invalid port: %w", err)
+	}
+
+	rt.Host, rt.Port = host, port
+	if err := rt.Validate(); err != nil {
+		return Route{}, err
+	}
+	return rt, nil
+}
+
+func (c *Config) AddRoutes(routes []Route) {
+	for _, rt := range routes {
+		replaced := false
+		for i := range c.Routes {
+			if c.Routes[i].Name == rt.Name && c.Routes[i].Path == rt.Path {
+				c.Routes[i] = rt
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			c.Routes = append(c.Routes, rt)
+		}
+	}
+}
+
+func (c *Config) FindRoutes(name string) []Route {
+	var routes []Route
+	for _, rt := range c.Routes {
+		if rt.Name == name {
+			routes = append(routes, rt)
+		}
+	}
+	return routes
+}
+
+func (c *Config) RouteNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, rt := range c.Routes {
+		if !seen[rt.Name] {
+			seen[rt.Name] = true
+			names = append(names, rt.Name)
+		}
+	}
+	return names
+}
+
+func (c *Config) validateRoutes() error {
+	seen := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, rt := range c.Routes {
+		if err := rt.Validate(); err != nil {
+			return err
+		}
+		key := rt.Name + strings.TrimSuffix(rt.Path, "/")
+		if seen[key] {
+			return fmt.Errorf("duplicate route %q", rt.String())
+		}
+		seen[key] = true
+		names[rt.Name] = true
+		if rt.HoldTimeout >= c.WriteTimeout {
+			return fmt.Errorf("route %q: hold_timeout must be shorter than the %ds write timeout", rt.Name, c.WriteTimeout)
+		}
+	}
+
+	if c.DefaultRoute != "" && !names[c.DefaultRoute] {
+		return fmt.Errorf("default route %q is not a known route", c.DefaultRoute)
+	}
+
+	return nil
+}
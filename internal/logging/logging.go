@@ -62,11 +62,14 @@ func (l *Logger) AccessLog() bool {
 type LogRequestParams struct {
 	Method       string
 	Host         string
+	Route        string
 	TargetPort   int
 	StatusCode   int
 	Latency      time.Duration
 	BytesWritten int64
 	Error        error
+	ReplayOf     string
+	Fault        string
 }
 
 func (l *Logger) LogRequest(ctx context.Context, p LogRequestParams) {
@@ -83,6 +86,16 @@ func (l *Logger) LogRequest(ctx context.Context, p LogRequestParams) {
 		slog.Int64("bytes", p.BytesWritten),
 	}
 
+	if p.Route != "" {
+		attrs = append(attrs, slog.String("route", p.Route))
+	}
+	if p.ReplayOf != "" {
+		attrs = append(attrs, slog.String("replay_of", p.ReplayOf))
+	}
+	if p.Fault != "" {
+		attrs = append(attrs, slog.String("fault", p.Fault))
+	}
+
 	if requestID, ok := ctx.Value(RequestIDKey).(string); ok {
 		attrs = append([]slog.Attr{slog.String("request_id", requestID)}, attrs...)
 	}
@@ -125,6 +138,33 @@ func (l *Logger) PortDenied(requestID string, port int, reason string) {
 	)
 }
 
+func (l *Logger) ClientDenied(requestID string, clientIP string, host string, reason string) {
+	l.Warn("client access denied",
+		slog.String("request_id", requestID),
+		slog.String("client_ip", clientIP),
+		slog.String("host", host),
+		slog.String("reason", reason),
+	)
+}
+
+func (l *Logger) AuthDenied(requestID string, clientIP string, host string, reason string) {
+	l.Warn("authentication failed",
+		slog.String("request_id", requestID),
+		slog.String("client_ip", clientIP),
+		slog.String("host", host),
+		slog.String("reason", reason),
+	)
+}
+
+func (l *Logger) UpstreamDenied(requestID string, host string, port int, reason string) {
+	l.Warn("upstream access denied",
+		slog.String("request_id", requestID),
+		slog.String("host", host),
+		slog.Int("port", port),
+		slog.String("reason", reason),
+	)
+}
+
 func (l *Logger) InvalidHost(requestID string, host string, reason string) {
 	l.Warn("invalid host header",
 		slog.String("request_id", requestID),
@@ -147,3 +187,18 @@ func (l *Logger) WebSocketUpgrade(requestID string, targetPort int) {
 		slog.Int("target_port", targetPort),
 	)
 }
+
+func (l *Logger) TCPSession(requestID string, host string, targetPort int, mode string, bytesIn, bytesOut int64, duration time.Duration) {
+	if !l.accessLog {
+		return
+	}
+	l.Info("tcp session closed",
+		slog.String("request_id", requestID),
+		slog.String("host", host),
+		slog.Int("target_port", targetPort),
+		slog.String("mode", mode),
+		slog.Int64("bytes_in", bytesIn),
+		slog.Int64("bytes_out", bytesOut),
+		slog.Duration("duration", duration),
+	)
+}
@@ -137,3 +137,27 @@ func TestLoggerMethods(t *testing.T) {
 	logger.ProxyError("req-3", 8000, errors.New("connection refused"))
 	logger.WebSocketUpgrade("req-4", 8080)
 }
+
+func TestLogRequestMarksFaults(t *testing.T) {
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	logger := NewLogger(false, true)
+	logger.LogRequest(context.Background(), LogRequestParams{
+		Method:     "GET",
+		Host:       "api.localhost",
+		StatusCode: 503,
+		Fault:      "flaky error=503",
+	})
+
+	w.Close()
+	os.Stdout = old
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+
+	if !strings.Contains(buf.String(), `fault="flaky error=503"`) {
+		t.Errorf("log output missing fault marker: %s", buf.String())
+	}
+}
@@ -0,0 +1,256 @@
+package proxy
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+const (
+	shareParam    = "httpsify_share"
+	sessionCookie = "httpsify_session"
+	shareKeyFile  = "share.key"
+)
+
+type secretFile struct {
+	once sync.Once
+	key  []byte
+	err  error
+}
+
+func (f *secretFile) load(path string) ([]byte, error) {
+	f.once.Do(func() {
+		f.key, f.err = loadOrCreateSecret(path)
+	})
+	return f.key, f.err
+}
+
+func loadOrCreateSecret(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) < 16 {
+			return nil, fmt.Errorf("invalid secret in %s", path)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read secret: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write secret: %w", err)
+	}
+	return key, nil
+}
+
+func (s *Server) shareSecret() ([]byte, error) {
+	return s.shareKey.load(filepath.Join(s.cfg.StateDir, shareKeyFile))
+}
+
+func signToken(key []byte, kind, label string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(kind + "\n" + label + "\n" + exp))
+	return exp + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func verifyToken(key []byte, kind, label, token string) (time.Time, bool) {
+	exp, _, ok := strings.Cut(token, ".")
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if !ok || err != nil {
+		return time.Time{}, false
+	}
+	expires := time.Unix(unix, 0)
+	if !time.Now().Before(expires) {
+		return time.Time{}, false
+	}
+	return expires, hmac.Equal([]byte(token), []byte(signToken(key, kind, label, expires)))
+}
+
+func (s *Server) signShare(kind, label string, expires time.Time) (string, error) {
+	key, err := s.shareSecret()
+	if err != nil {
+		return "", err
+	}
+	return signToken(key, kind, label, expires), nil
+}
+
+func (s *Server) verifyShare(kind, label, token string) (time.Time, bool) {
+	key, err := s.shareSecret()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return verifyToken(key, kind, label, token)
+}
+
+func (s *Server) ShareURL(name string, ttl time.Duration) (string, error) {
+	name = strings.TrimSuffix(name, "/")
+	for _, rc := range s.cfg.Routes {
+		label := rc.Name + strings.TrimSuffix(rc.Path, "/")
+		if label != name {
+			continue
+		}
+		if rc.Auth == nil || !rc.Auth.ShareLinks {
+			return "", fmt.Errorf("route %q does not enable share_links", name)
+		}
+		token, err := s.signShare("share", label, time.Now().Add(ttl))
+		if err != nil {
+			return "", err
+		}
+		u, err := url.Parse(s.routeURL(rc))
+		if err != nil {
+			return "", err
+		}
+		if u.Path == "" {
+			u.Path = "/"
+		}
+		u.RawQuery = url.Values{shareParam: {token}}.Encode()
+		return u.String(), nil
+	}
+	return "", fmt.Errorf("unknown route %q", name)
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, requestID string, rt *route) bool {
+	a := rt.auth
+	label := rt.label()
+
+	if a.ShareLinks {
+		if token := r.URL.Query().Get(shareParam); token != "" {
+			s.redeemShare(w, r, requestID, rt, token)
+			return false
+		}
+		for _, c := range r.Cookies() {
+			if c.Name != sessionCookie {
+				continue
+			}
+			if _, ok := s.verifyShare("session", label, c.Value); ok {
+				stripCredentials(r, a)
+				return true
+			}
+		}
+	}
+
+	if user, password, ok := r.BasicAuth(); ok && a.CheckPassword(user, password) {
+		stripCredentials(r, a)
+		return true
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && a.CheckToken(token) {
+		stripCredentials(r, a)
+		return true
+	}
+
+	reason := "missing credentials"
+	if r.Header.Get("Authorization") != "" {
+		reason = "invalid credentials"
+	}
+	s.denyAuth(w, r, requestID, rt, reason)
+	return false
+}
+
+func (s *Server) redeemShare(w http.ResponseWriter, r *http.Request, requestID string, rt *route, token string) {
+	expires, ok := s.verifyShare("share", rt.label(), token)
+	if !ok {
+		s.denyAuth(w, r, requestID, rt, "invalid or expired share link")
+		return
+	}
+	session, err := s.signShare("session", rt.label(), expires)
+	if err != nil {
+		s.handleError(w, r, requestID, http.StatusInternalServerError, "Failed to create session", err.Error(), "")
+		return
+	}
+
+	path := rt.path
+	if path == "" {
+		path = "/"
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    session,
+		Path:     path,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   !isPlainHTTP(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	u := *r.URL
+	q := u.Query()
+	q.Del(shareParam)
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.RequestURI(), http.StatusFound)
+}
+
+func (s *Server) denyAuth(w http.ResponseWriter, r *http.Request, requestID string, rt *route, reason string) {
+	realm := strconv.Quote("httpsify " + rt.label())
+	switch {
+	case len(rt.auth.Users) > 0:
+		w.Header().Set("WWW-Authenticate", "Basic realm="+realm+", charset=\"UTF-8\"")
+	case len(rt.auth.Tokens) > 0:
+		w.Header().Set("WWW-Authenticate", "Bearer realm="+realm)
+	}
+	example := ""
+	if rt.auth.ShareLinks {
+		example = "httpsify share " + rt.label()
+	}
+	s.handleError(w, r, requestID, http.StatusUnauthorized,
+		fmt.Sprintf("Authentication required for %s", rt.label()),
+		authHint(rt.auth),
+		example)
+	s.logger.AuthDenied(requestID, remoteIP(r.RemoteAddr).String(), r.Host, reason)
+}
+
+func authHint(a *config.RouteAuth) string {
+	var methods []string
+	if len(a.Users) > 0 {
+		methods = append(methods, "basic auth")
+	}
+	if len(a.Tokens) > 0 {
+		methods = append(methods, "a bearer token")
+	}
+	if a.ShareLinks {
+		methods = append(methods, "a share link")
+	}
+	return "Sign in with " + strings.Join(methods, ", ")
+}
+
+func stripCredentials(r *http.Request, a *config.RouteAuth) {
+	if a.UsesAuthorization() {
+		r.Header.Del("Authorization")
+	}
+
+	var kept []string
+	for _, line := range r.Header.Values("Cookie") {
+		for _, part := range strings.Split(line, ";") {
+			part = strings.TrimSpace(part)
+			if name, _, _ := strings.Cut(part, "="); part != "" && name != sessionCookie {
+				kept = append(kept, part)
+			}
+		}
+	}
+	r.Header.Del("Cookie")
+	if len(kept) > 0 {
+		r.Header.Set("Cookie", strings.Join(kept, "; "))
+	}
+}
@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+	"github.com/imcanugur/httpsify/internal/logging"
+)
+
+func TestRouteAuth(t *testing.T) {
+	var seenAuth, seenCookie string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenAuth, seenCookie = r.Header.Get("Authorization"), r.Header.Get("Cookie")
+	}))
+	defer backend.Close()
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{{Name: "shop", Port: port, Auth: &config.RouteAuth{
+		Users:      map[string]string{"designer": "s3cret"},
+		Tokens:     []string{"ci-token"},
+		ShareLinks: true,
+	}}}
+	s := newTestServer(t, cfg)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("credentials", func(t *testing.T) {
+		tests := []struct {
+			name string
+			set  func(*http.Request)
+			want int
+		}{
+			{"none", func(*http.Request) {}, http.StatusUnauthorized},
+			{"basic", func(r *http.Request) { r.SetBasicAuth("designer", "s3cret") }, http.StatusOK},
+			{"wrong password", func(r *http.Request) { r.SetBasicAuth("designer", "nope") }, http.StatusUnauthorized},
+			{"bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer ci-token") }, http.StatusOK},
+			{"wrong bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer other") }, http.StatusUnauthorized},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				seenAuth = "unset"
+				req := newLocalRequest("GET", "https://shop.localhost/", nil)
+				tt.set(req)
+				rr := serve(req)
+				if rr.Code != tt.want {
+					t.Fatalf("status = %d, want %d", rr.Code, tt.want)
+				}
+				if tt.want == http.StatusOK && seenAuth != "" {
+					t.Errorf("backend saw Authorization %q", seenAuth)
+				}
+				if tt.want == http.StatusUnauthorized && !strings.HasPrefix(rr.Header().Get("WWW-Authenticate"), "Basic ") {
+					t.Errorf("WWW-Authenticate = %q", rr.Header().Get("WWW-Authenticate"))
+				}
+			})
+		}
+	})
+
+	t.Run("share link", func(t *testing.T) {
+		link, err := s.ShareURL("shop", time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := serve(newLocalRequest("GET", link+"&page=2", nil))
+		if rr.Code != http.StatusFound || rr.Header().Get("Location") != "/?page=2" {
+			t.Fatalf("redeem = %d %q, want redirect to /?page=2", rr.Code, rr.Header().Get("Location"))
+		}
+		cookies := rr.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != sessionCookie || !cookies[0].HttpOnly || !cookies[0].Secure {
+			t.Fatalf("cookies = %+v, want one secure HttpOnly session cookie", cookies)
+		}
+
+		req := newLocalRequest("GET", "https://shop.localhost/?page=2", nil)
+		req.AddCookie(cookies[0])
+		req.AddCookie(&http.Cookie{Name: "app", Value: "1"})
+		if rr := serve(req); rr.Code != http.StatusOK {
+			t.Fatalf("session status = %d, want 200", rr.Code)
+		}
+		if seenCookie != "app=1" {
+			t.Errorf("backend saw Cookie %q, want app=1", seenCookie)
+		}
+
+		tampered := strings.Replace(link, shareParam+"=", shareParam+"=1", 1)
+		if rr := serve(newLocalRequest("GET", tampered, nil)); rr.Code != http.StatusUnauthorized {
+			t.Errorf("tampered link status = %d, want 401", rr.Code)
+		}
+		expired, _ := s.signShare("share", "shop", time.Now().Add(-time.Minute))
+		if rr := serve(newLocalRequest("GET", "https://shop.localhost/?"+shareParam+"="+expired, nil)); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expired link status = %d, want 401", rr.Code)
+		}
+	})
+
+	t.Run("share key persists", func(t *testing.T) {
+		link, _ := s.ShareURL("shop", time.Hour)
+		rr := httptest.NewRecorder()
+		NewServer(cfg, logging.NewLogger(false, false)).ServeHTTP(rr, newLocalRequest("GET", link, nil))
+		if rr.Code != http.StatusFound {
+			t.Errorf("restarted server status = %d, want 302", rr.Code)
+		}
+		if _, err := s.ShareURL("missing", time.Hour); err == nil {
+			t.Error("ShareURL for unknown route succeeded")
+		}
+	})
+}
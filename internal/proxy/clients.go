@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func (s *Server) buildClientACLs() map[string]config.ClientACL {
+	acls := make(map[string]config.ClientACL)
+	for _, listener := range []string{config.ListenerHTTPS, config.ListenerHTTP, config.ListenerTCP} {
+		acls[listener], _ = config.ParseClientACL(s.cfg.ListenerClients(listener))
+	}
+	return acls
+}
+
+func remoteIP(remoteAddr string) net.IP {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+func (s *Server) clientAllowed(ip net.IP, listener string, rt *route) bool {
+	acl := s.clientACLs[listener]
+	name, port := config.DashboardName, 0
+	if rt != nil {
+		if rt.clients != nil {
+			acl = *rt.clients
+		}
+		name, port = rt.name, rt.port
+	}
+	if acl.Allows(ip) {
+		return true
+	}
+	return ip != nil && config.IsLANAddress(ip) && s.cfg.LANAllows(name, port)
+}
+
+func requestListener(r *http.Request) string {
+	if isPlainHTTP(r) {
+		return config.ListenerHTTP
+	}
+	return config.ListenerHTTPS
+}
+
+func (s *Server) checkClient(w http.ResponseWriter, r *http.Request, requestID string, rt *route) bool {
+	ip := remoteIP(r.RemoteAddr)
+	if s.clientAllowed(ip, requestListener(r), rt) {
+		return true
+	}
+
+	s.handleError(w, r, requestID, http.StatusForbidden,
+		fmt.Sprintf("Client %s is not allowed", ip),
+		"Only loopback clients are served by default. Use --lan to open routes to the local network, or --allow-clients for specific addresses",
+		"--allow-clients 192.168.1.0/24")
+	s.logger.ClientDenied(requestID, ip.String(), r.Host, "client not in allow list")
+	return false
+}
@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestClientAllowlist(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.LAN = []string{"shop"}
+	cfg.Routes = []config.Route{
+		{Name: "shop", Port: port},
+		{Name: "admin", Port: port},
+		{Name: "partner", Port: port, AllowClients: []string{"203.0.113.0/24"}},
+	}
+	s := newTestServer(t, cfg)
+
+	tests := []struct {
+		host   string
+		client string
+		want   int
+	}{
+		{"shop.localhost", "127.0.0.1", http.StatusOK},
+		{"shop.localhost", "192.168.1.20", http.StatusOK},
+		{"shop.localhost", "203.0.113.9", http.StatusForbidden},
+		{"admin.localhost", "192.168.1.20", http.StatusForbidden},
+		{fmt.Sprintf("%d.localhost", port), "192.168.1.20", http.StatusForbidden},
+		{"partner.localhost", "203.0.113.9", http.StatusOK},
+		{"partner.localhost", "192.168.1.20", http.StatusForbidden},
+		{"localhost", "192.168.1.20", http.StatusForbidden},
+		{"nope.localhost", "192.168.1.20", http.StatusNotFound},
+		{"nope.localhost", "127.0.0.1", http.StatusNotFound},
+		{"app.example.com", "192.168.1.20", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host+"/"+tt.client, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "https://"+tt.host+"/", nil)
+			req.RemoteAddr = net.JoinHostPort(tt.client, "40000")
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rr.Code, tt.want)
+			}
+			var resp ErrorResponse
+			json.NewDecoder(rr.Body).Decode(&resp)
+			if tt.want == http.StatusForbidden && !strings.Contains(resp.Error, tt.client) {
+				t.Errorf("error response = %+v; want JSON naming %s", resp, tt.client)
+			}
+			if tt.want == http.StatusNotFound && (len(resp.KnownRoutes) > 0) != (tt.client == "127.0.0.1") {
+				t.Errorf("known routes = %v; want them listed only for allowed clients", resp.KnownRoutes)
+			}
+		})
+	}
+}
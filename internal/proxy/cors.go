@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+const corsLogSize = 50
+
+type CORSEvent struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	Host      string    `json:"host"`
+	Origin    string    `json:"origin"`
+	Method    string    `json:"method"`
+	Preflight bool      `json:"preflight"`
+	Allowed   bool      `json:"allowed"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+type corsLog struct {
+	mu     sync.Mutex
+	events []CORSEvent
+}
+
+func (l *corsLog) add(e CORSEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, e)
+	if len(l.events) > corsLogSize {
+		l.events = l.events[len(l.events)-corsLogSize:]
+	}
+}
+
+func (l *corsLog) recent() []CORSEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	events := make([]CORSEvent, len(l.events))
+	for i, e := range l.events {
+		events[len(events)-1-i] = e
+	}
+	return events
+}
+
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+func corsRequestHeaders(r *http.Request) []string {
+	var headers []string
+	for _, v := range r.Header.Values("Access-Control-Request-Headers") {
+		headers = append(headers, config.ParseList(v)...)
+	}
+	return headers
+}
+
+func checkCORS(p *config.CORSPolicy, r *http.Request, origin string) string {
+	if !p.AllowsOrigin(origin) {
+		return fmt.Sprintf("origin %s is not in allow_origins", origin)
+	}
+	method := r.Method
+	if isPreflight(r) {
+		method = r.Header.Get("Access-Control-Request-Method")
+	}
+	if !p.AllowsMethod(method) {
+		return fmt.Sprintf("method %s is not in allow_methods", method)
+	}
+	if h := p.DisallowedHeader(corsRequestHeaders(r)); h != "" {
+		return fmt.Sprintf("header %s is not in allow_headers", h)
+	}
+	return ""
+}
+
+func (s *Server) handleCORS(w http.ResponseWriter, r *http.Request, requestID string, rt *route) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+
+	p := rt.cors
+	preflight := isPreflight(r)
+	reason := checkCORS(p, r, origin)
+	s.cors.add(CORSEvent{
+		Time:      time.Now(),
+		RequestID: requestID,
+		Host:      r.Host,
+		Origin:    origin,
+		Method:    r.Method,
+		Preflight: preflight,
+		Allowed:   reason == "",
+		Reason:    reason,
+	})
+
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	if preflight {
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+	}
+
+	if reason != "" {
+		if preflight && !p.ForwardPreflight {
+			s.writeJSONError(w, http.StatusForbidden, "CORS preflight rejected", reason, "")
+			return true
+		}
+		return false
+	}
+
+	if p.AllowsAnyOrigin() && !p.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if !preflight {
+		if len(p.ExposeHeaders) > 0 {
+			h.Set("Access-Control-Expose-Headers", strings.Join(p.ExposeHeaders, ", "))
+		}
+		return false
+	}
+
+	h.Set("Access-Control-Allow-Methods", strings.Join(p.Methods(), ", "))
+	if len(p.AllowHeaders) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(p.AllowHeaders, ", "))
+	} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		h.Set("Access-Control-Allow-Headers", requested)
+	}
+	if p.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(p.MaxAge))
+	}
+
+	if p.ForwardPreflight {
+		return false
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+func stripCORSHeaders(h http.Header) {
+	for key := range h {
+		if strings.HasPrefix(key, "Access-Control-") {
+			delete(h, key)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestCORSPolicy(t *testing.T) {
+	var backendHits int
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendHits++
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{{
+		Name: "api",
+		Port: port,
+		CORS: &config.CORSPolicy{
+			AllowOrigins:     []string{"https://*.localhost"},
+			AllowCredentials: true,
+			AllowHeaders:     []string{"Content-Type", "Authorization"},
+			ExposeHeaders:    []string{"X-Request-ID"},
+			MaxAge:           600,
+		},
+	}}
+	s := newTestServer(t, cfg)
+
+	tests := []struct {
+		name       string
+		method     string
+		origin     string
+		reqMethod  string
+		reqHeaders string
+		wantStatus int
+		wantOrigin string
+		wantHits   int
+	}{
+		{"preflight allowed", "OPTIONS", "https://3000.localhost", "PUT", "content-type", http.StatusNoContent, "https://3000.localhost", 0},
+		{"preflight bad origin", "OPTIONS", "https://evil.example", "PUT", "", http.StatusForbidden, "", 0},
+		{"preflight bad header", "OPTIONS", "https://3000.localhost", "PUT", "X-Secret", http.StatusForbidden, "", 0},
+		{"simple allowed", "GET", "https://3000.localhost", "", "", http.StatusOK, "https://3000.localhost", 1},
+		{"simple rejected", "GET", "https://evil.example", "", "", http.StatusOK, "", 1},
+		{"no origin", "GET", "", "", "", http.StatusOK, "", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backendHits = 0
+			req := newLocalRequest(tt.method, "https://api.localhost/items", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.reqMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.reqMethod)
+			}
+			if tt.reqHeaders != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.reqHeaders)
+			}
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if backendHits != tt.wantHits {
+				t.Errorf("backend hits = %d, want %d", backendHits, tt.wantHits)
+			}
+			if tt.wantStatus == http.StatusNoContent && rr.Header().Get("Access-Control-Max-Age") != "600" {
+				t.Errorf("Access-Control-Max-Age = %q, want 600", rr.Header().Get("Access-Control-Max-Age"))
+			}
+		})
+	}
+
+	events := s.cors.recent()
+	if len(events) != 5 {
+		t.Fatalf("recorded %d CORS events, want 5", len(events))
+	}
+	if e := events[2]; !e.Preflight || e.Allowed || !strings.Contains(e.Reason, "X-Secret") {
+		t.Errorf("event = %+v, want rejected preflight naming X-Secret", e)
+	}
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://localhost/", nil))
+	if !strings.Contains(rr.Body.String(), "CORS Activity") || !strings.Contains(rr.Body.String(), "Rejected") {
+		t.Error("landing page does not show CORS activity")
+	}
+}
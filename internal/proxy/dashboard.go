@@ -0,0 +1,250 @@
+package proxy
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"html"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/version"
+)
+
+const (
+	dashboardCookie    = "httpsify_dashboard"
+	csrfCookie         = "httpsify_csrf"
+	dashboardTokenFile = "dashboard.token"
+	dashboardTTL       = 24 * time.Hour
+	csrfHeader         = "X-CSRF-Token"
+	csrfField          = "csrf_token"
+)
+
+//go:embed login.html
+var loginPageHTML string
+
+type dashboardAccess int
+
+const (
+	accessDenied dashboardAccess = iota
+	accessLoopback
+	accessSession
+	accessToken
+)
+
+func (s *Server) dashboardKey() ([]byte, error) {
+	return s.dashKey.load(s.dashboardTokenPath())
+}
+
+func (s *Server) dashboardTokenPath() string {
+	return filepath.Join(s.cfg.StateDir, dashboardTokenFile)
+}
+
+func (s *Server) DashboardToken() (string, error) {
+	key, err := s.dashboardKey()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+func (s *Server) dashboardAccess(r *http.Request) dashboardAccess {
+	key, err := s.dashboardKey()
+	if err == nil {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(hex.EncodeToString(key))) == 1 {
+				return accessToken
+			}
+		}
+		if c, err := r.Cookie(dashboardCookie); err == nil {
+			if _, ok := verifyToken(key, "dashboard", "", c.Value); ok {
+				return accessSession
+			}
+		}
+	}
+	if ip := remoteIP(r.RemoteAddr); ip != nil && ip.IsLoopback() {
+		return accessLoopback
+	}
+	return accessDenied
+}
+
+func (s *Server) csrfToken(r *http.Request) string {
+	key, err := s.dashboardKey()
+	if err != nil {
+		return ""
+	}
+	var session string
+	if c, err := r.Cookie(dashboardCookie); err == nil {
+		if _, ok := verifyToken(key, "dashboard", "", c.Value); ok {
+			session = c.Value
+		}
+	}
+	if c, err := r.Cookie(csrfCookie); err == nil && session == "" {
+		session = c.Value
+	}
+	if session == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("csrf\n" + session))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Server) startCSRFSession(w http.ResponseWriter, r *http.Request) {
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	c := &http.Cookie{
+		Name:     csrfCookie,
+		Value:    hex.EncodeToString(nonce),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	}
+	http.SetCookie(w, c)
+	r.AddCookie(c)
+}
+
+func (s *Server) validCSRF(r *http.Request) bool {
+	want := s.csrfToken(r)
+	got := r.Header.Get(csrfHeader)
+	if got == "" {
+		got = r.PostFormValue(csrfField)
+	}
+	return want != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func (s *Server) serveDashboard(w http.ResponseWriter, r *http.Request, requestID string) {
+	if r.URL.Path == "/login" {
+		s.serveLogin(w, r, requestID)
+		return
+	}
+
+	access := s.dashboardAccess(r)
+	if access == accessDenied {
+		if r.Method == http.MethodGet && !strings.HasPrefix(r.URL.Path, "/api/") {
+			s.renderLogin(w, http.StatusUnauthorized, r.URL.RequestURI(), "")
+		} else {
+			s.writeJSONError(w, http.StatusUnauthorized, "Dashboard token required",
+				"Sign in at /login or send Authorization: Bearer <token>",
+				"cat "+s.dashboardTokenPath())
+		}
+		s.logger.AuthDenied(requestID, remoteIP(r.RemoteAddr).String(), r.Host, "dashboard token required")
+		return
+	}
+	// Loopback browsers have no login session; tie their CSRF token to a random one.
+	if access == accessLoopback && isSafeMethod(r.Method) && s.csrfToken(r) == "" {
+		s.startCSRFSession(w, r)
+	}
+	if !isSafeMethod(r.Method) && access != accessToken && !s.validCSRF(r) {
+		s.writeJSONError(w, http.StatusForbidden, "Missing or invalid CSRF token",
+			"Send the token from the dashboard page in the "+csrfHeader+" header", "")
+		return
+	}
+
+	switch r.URL.Path {
+	case "/logout":
+		if r.Method != http.MethodPost {
+			s.writeJSONError(w, http.StatusMethodNotAllowed, "Use POST to sign out", "", "")
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: dashboardCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true, Secure: true})
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	case "/inspector":
+		s.serveInspectorPage(w, r)
+	case "/inspector/replay":
+		s.serveReplayForm(w, r)
+	case "/inspector/import":
+		s.serveHARImportForm(w, r)
+	case "/api/har":
+		s.serveHARAPI(w, r)
+	case "/faults":
+		s.serveFaultToggle(w, r)
+	case "/api/exchanges":
+		s.serveExchangesAPI(w, r)
+	case "/mirrors":
+		s.serveMirrorsPage(w, r)
+	case "/api/mirrors", "/api/mirrors/diffs":
+		s.serveMirrorsAPI(w, r)
+	default:
+		if strings.HasPrefix(r.URL.Path, "/api/exchanges/") {
+			s.serveExchangesAPI(w, r)
+			return
+		}
+		if r.URL.Path == "/api/faults" || strings.HasPrefix(r.URL.Path, "/api/faults/") {
+			s.serveFaultsAPI(w, r)
+			return
+		}
+		s.serveLandingPage(w, r, access == accessSession)
+	}
+}
+
+func (s *Server) serveLogin(w http.ResponseWriter, r *http.Request, requestID string) {
+	next := r.FormValue("next")
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/login") {
+		next = "/"
+	}
+
+	if r.Method != http.MethodPost {
+		if s.dashboardAccess(r) == accessSession {
+			http.Redirect(w, r, next, http.StatusSeeOther)
+			return
+		}
+		s.renderLogin(w, http.StatusOK, next, "")
+		return
+	}
+
+	key, err := s.dashboardKey()
+	if err != nil {
+		s.writeJSONError(w, http.StatusInternalServerError, "Dashboard token unavailable", err.Error(), "")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(r.PostFormValue("token"))), []byte(hex.EncodeToString(key))) != 1 {
+		s.logger.AuthDenied(requestID, remoteIP(r.RemoteAddr).String(), r.Host, "invalid dashboard token")
+		s.renderLogin(w, http.StatusUnauthorized, next, "Invalid token")
+		return
+	}
+
+	expires := time.Now().Add(dashboardTTL)
+	http.SetCookie(w, &http.Cookie{
+		Name:     dashboardCookie,
+		Value:    signToken(key, "dashboard", "", expires),
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+func (s *Server) renderLogin(w http.ResponseWriter, status int, next, errMsg string) {
+	errorClass := ""
+	if errMsg == "" {
+		errorClass = "hidden"
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	output := strings.NewReplacer(
+		"{{.TOKEN_PATH}}", html.EscapeString(s.dashboardTokenPath()),
+		"{{.ERROR_CLASS}}", errorClass,
+		"{{.ERROR}}", html.EscapeString(errMsg),
+		"{{.NEXT}}", html.EscapeString(next),
+		"{{.VERSION}}", version.Get().Version,
+	).Replace(loginPageHTML)
+	w.Write([]byte(output))
+}
@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestDashboardAuth(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.LAN = []string{"*"}
+	s := newTestServer(t, cfg)
+	token, err := s.DashboardToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(cfg.StateDir, dashboardTokenFile)); strings.TrimSpace(string(data)) != token {
+		t.Fatalf("token file = %q, want %q", data, token)
+	}
+
+	serve := func(method, target, client string, body io.Reader, set func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "https://localhost"+target, body)
+		req.RemoteAddr = net.JoinHostPort(client, "40000")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if set != nil {
+			set(req)
+		}
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		return rr
+	}
+	const lan = "192.168.1.20"
+
+	loopback, csrf := loopbackCSRF(s)
+	if rr := serve("GET", "/", "127.0.0.1", nil, func(r *http.Request) { r.AddCookie(loopback) }); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), csrf) {
+		t.Errorf("loopback dashboard = %d, want 200 with CSRF token", rr.Code)
+	}
+	if other, token := loopbackCSRF(s); other.Value == loopback.Value || token == csrf {
+		t.Error("loopback browsers share a CSRF token")
+	}
+	if rr := serve("GET", "/", lan, nil, nil); rr.Code != http.StatusUnauthorized || !strings.Contains(rr.Body.String(), `action="/login"`) {
+		t.Errorf("LAN dashboard = %d, want 401 login page", rr.Code)
+	}
+	if rr := serve("GET", "/api/status", lan, nil, nil); rr.Code != http.StatusUnauthorized || !strings.Contains(rr.Header().Get("Content-Type"), "json") {
+		t.Errorf("LAN API = %d %s, want 401 JSON", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	bearer := func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	if rr := serve("GET", "/", lan, nil, bearer); rr.Code != http.StatusOK {
+		t.Errorf("LAN bearer = %d, want 200", rr.Code)
+	}
+
+	if rr := serve("POST", "/login", lan, strings.NewReader("token=nope"), nil); rr.Code != http.StatusUnauthorized || !strings.Contains(rr.Body.String(), "Invalid token") {
+		t.Errorf("bad login = %d, want 401 with error", rr.Code)
+	}
+	rr := serve("POST", "/login", lan, strings.NewReader("token="+token+"&next=//evil.example"), nil)
+	cookies := rr.Result().Cookies()
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/" || len(cookies) != 1 || !cookies[0].HttpOnly {
+		t.Fatalf("login = %d %q %+v, want redirect to / with session cookie", rr.Code, rr.Header().Get("Location"), cookies)
+	}
+	session := func(r *http.Request) { r.AddCookie(cookies[0]) }
+	if rr := serve("GET", "/", lan, nil, session); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `action="/logout" class=""`) {
+		t.Errorf("session dashboard = %d, want 200 with sign-out form", rr.Code)
+	}
+
+	if rr := serve("POST", "/logout", lan, strings.NewReader(""), session); rr.Code != http.StatusForbidden {
+		t.Errorf("logout without CSRF = %d, want 403", rr.Code)
+	}
+	if rr := serve("POST", "/", "127.0.0.1", strings.NewReader(""), nil); rr.Code != http.StatusForbidden {
+		t.Errorf("loopback POST without CSRF = %d, want 403", rr.Code)
+	}
+	if rr := serve("POST", "/logout", lan, strings.NewReader(csrfField+"="+csrf), session); rr.Code != http.StatusForbidden {
+		t.Errorf("logout with another session's CSRF token = %d, want 403", rr.Code)
+	}
+	req := httptest.NewRequest("GET", "https://localhost/", nil)
+	session(req)
+	sessionCSRF := s.csrfToken(req)
+	if rr := serve("POST", "/logout", lan, strings.NewReader(csrfField+"="+sessionCSRF), session); rr.Code != http.StatusSeeOther {
+		t.Errorf("logout = %d, want 303", rr.Code)
+	}
+}
+
+func loopbackCSRF(s *Server) (*http.Cookie, string) {
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://localhost/inspector", nil))
+	req := newLocalRequest("GET", "https://localhost/inspector", nil)
+	var cookie *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == csrfCookie {
+			cookie = c
+			req.AddCookie(c)
+		}
+	}
+	return cookie, s.csrfToken(req)
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/imcanugur/httpsify/internal/version"
+)
+
+//go:embed error.html
+var errorPageHTML string
+
+func prefersHTML(h http.Header) bool {
+	htmlQ, jsonQ := -1.0, -1.0
+	for _, part := range strings.Split(h.Get("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(p), "q="); ok {
+				q, _ = strconv.ParseFloat(v, 64)
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case "text/html":
+			htmlQ = max(htmlQ, q)
+		case "application/json":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return htmlQ > 0 && htmlQ > jsonQ
+}
+
+func describeProxyError(err error, rt *route) (string, string) {
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout(), errors.Is(err, context.DeadlineExceeded), errors.Is(err, syscall.ETIMEDOUT):
+		return "Request timed out", "The backend service took too long to respond"
+	case backendDown(err):
+		hint := fmt.Sprintf("No service is listening on %s", rt.upstream())
+		if rt.hold > 0 {
+			hint += fmt.Sprintf(" (waited %s for it to restart)", rt.hold)
+		}
+		return "Connection refused", hint
+	case connDropped(err):
+		return "Connection reset", fmt.Sprintf("The service on %s closed the connection before answering; it may have crashed or restarted", rt.upstream())
+	}
+	return "Backend service unavailable", fmt.Sprintf("Make sure a service is running on %s", rt.upstream())
+}
+
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, requestID string, statusCode int, resp ErrorResponse) {
+	if !prefersHTML(r.Header) {
+		s.writeErrorResponse(w, statusCode, resp)
+		return
+	}
+
+	hintText := resp.Hint
+	if hintText == "" {
+		hintText = http.StatusText(statusCode)
+	}
+	retryClass := "hidden"
+	if r.Method == http.MethodGet && (statusCode == http.StatusBadGateway || statusCode == http.StatusServiceUnavailable || statusCode == http.StatusGatewayTimeout) {
+		retryClass = ""
+	}
+	exampleClass := ""
+	if resp.Example == "" {
+		exampleClass = "hidden"
+	}
+	var routes strings.Builder
+	for _, name := range resp.KnownRoutes {
+		routes.WriteString(fmt.Sprintf(`
+                <a href="%s" class="port-item"><span class="port-value">%s</span></a>`,
+			html.EscapeString("https://"+s.cfg.RouteHost(name)), html.EscapeString(s.cfg.RouteHost(name))))
+	}
+	routesClass := ""
+	if len(resp.KnownRoutes) == 0 {
+		routesClass = "hidden"
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	output := strings.NewReplacer(
+		"{{.STATUS}}", strconv.Itoa(statusCode),
+		"{{.STATUS_TEXT}}", html.EscapeString(http.StatusText(statusCode)),
+		"{{.ERROR}}", html.EscapeString(resp.Error),
+		"{{.HINT}}", html.EscapeString(hintText),
+		"{{.RETRY_CLASS}}", retryClass,
+		"{{.REQUEST_ID}}", html.EscapeString(requestID),
+		"{{.HOST}}", html.EscapeString(r.Host),
+		"{{.EXAMPLE_CLASS}}", exampleClass,
+		"{{.EXAMPLE}}", html.EscapeString(resp.Example),
+		"{{.ROUTES_CLASS}}", routesClass,
+		"{{.ROUTES}}", routes.String(),
+		"{{.VERSION}}", version.Get().Version,
+	).Replace(errorPageHTML)
+	w.Write([]byte(output))
+}
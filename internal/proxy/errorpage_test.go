@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestPrefersHTML(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", true},
+		{"TEXT/HTML", true},
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"application/json, text/html", false},
+		{"text/html;q=0.5, application/json", false},
+		{"application/json;q=0.1, text/html", true},
+		{"text/html;q=0", false},
+	}
+	for _, tt := range tests {
+		h := http.Header{"Accept": {tt.accept}}
+		if got := prefersHTML(h); got != tt.want {
+			t.Errorf("prefersHTML(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestDescribeProxyError(t *testing.T) {
+	rt := &route{host: "127.0.0.1", port: 3000}
+	dial := func(err error) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", err)}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"refused", dial(syscall.ECONNREFUSED), "Connection refused"},
+		{"missing socket", &net.OpError{Op: "dial", Net: "unix", Err: os.NewSyscallError("connect", syscall.ENOENT)}, "Connection refused"},
+		{"reset", &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, "Connection reset"},
+		{"eof", fmt.Errorf("readLoopPeekFailLocked: %w", io.EOF), "Connection reset"},
+		{"deadline", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, "Request timed out"},
+		{"context", fmt.Errorf("proxy: %w", context.DeadlineExceeded), "Request timed out"},
+		{"etimedout", dial(syscall.ETIMEDOUT), "Request timed out"},
+		{"other", errors.New("tls: handshake failure"), "Backend service unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := describeProxyError(tt.err, rt); got != tt.want {
+				t.Errorf("describeProxyError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorPages(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{{Name: "shop", Port: port}}
+	s := newTestServer(t, cfg)
+
+	const browser = "text/html,application/xhtml+xml,*/*;q=0.8"
+	tests := []struct {
+		name     string
+		method   string
+		host     string
+		accept   string
+		status   int
+		html     bool
+		contains []string
+	}{
+		{"browser", "GET", "shop.localhost", browser, http.StatusBadGateway, true,
+			[]string{"Connection refused", "No service is listening on port " + strconv.Itoa(port), `class="retry "`, "Request ID"}},
+		{"browser post", "POST", "shop.localhost", browser, http.StatusBadGateway, true, []string{`class="retry hidden"`}},
+		{"api client", "GET", "shop.localhost", "application/json", http.StatusBadGateway, false, []string{`"error":"Connection refused"`}},
+		{"fetch", "GET", "shop.localhost", "*/*", http.StatusBadGateway, false, []string{`"hint":"No service is listening`}},
+		{"unknown route", "GET", "nope.localhost", browser, http.StatusNotFound, true,
+			[]string{"Known Routes", `href="https://shop.localhost"`, `class="retry hidden"`}},
+		{"unknown route json", "GET", "nope.localhost", "", http.StatusNotFound, false, []string{`"known_routes":["shop"]`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newLocalRequest(tt.method, "https://"+tt.host+"/", nil)
+			req.Header.Set("Accept", tt.accept)
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, req)
+			if rr.Code != tt.status {
+				t.Errorf("status = %d, want %d", rr.Code, tt.status)
+			}
+			if got := strings.HasPrefix(rr.Header().Get("Content-Type"), "text/html"); got != tt.html {
+				t.Errorf("Content-Type = %q, want html %v", rr.Header().Get("Content-Type"), tt.html)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(rr.Body.String(), want) {
+					t.Errorf("body does not contain %q:\n%s", want, rr.Body.String())
+				}
+			}
+		})
+	}
+}
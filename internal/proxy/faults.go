@@ -0,0 +1,233 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+var errFaultNotFound = errors.New("fault rule not found")
+
+type FaultStatus struct {
+	config.FaultRule
+	Injected int64 `json:"injected"`
+}
+
+type faultSet struct {
+	mu     sync.Mutex
+	rules  []config.FaultRule
+	counts map[string]int64
+}
+
+func newFaultSet(rules []config.FaultRule) *faultSet {
+	return &faultSet{rules: append([]config.FaultRule(nil), rules...), counts: make(map[string]int64)}
+}
+
+type faultPlan struct {
+	rule     string
+	delay    time.Duration
+	status   int
+	reset    bool
+	truncate int
+	wsDrop   bool
+	wsAfter  time.Duration
+}
+
+func (p *faultPlan) String() string {
+	if p == nil {
+		return ""
+	}
+	parts := []string{p.rule}
+	if p.delay > 0 {
+		parts = append(parts, "latency="+p.delay.String())
+	}
+	switch {
+	case p.reset:
+		parts = append(parts, "reset")
+	case p.status != 0:
+		parts = append(parts, "error="+strconv.Itoa(p.status))
+	case p.truncate >= 0:
+		parts = append(parts, "truncate="+strconv.Itoa(p.truncate))
+	}
+	if p.wsDrop {
+		parts = append(parts, "ws_drop="+p.wsAfter.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+func roll(rate float64) bool {
+	return rate > 0 && rand.Float64()*100 < rate
+}
+
+func (fs *faultSet) plan(route, method, path string, websocket bool) *faultPlan {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for _, f := range fs.rules {
+		if f.Disabled || !f.Matches(route, method, path) {
+			continue
+		}
+		p := &faultPlan{rule: f.Name, truncate: -1, delay: time.Duration(f.LatencyMS) * time.Millisecond}
+		if f.JitterMS > 0 {
+			p.delay += time.Duration(rand.IntN(f.JitterMS+1)) * time.Millisecond
+		}
+		switch {
+		case roll(f.ResetRate):
+			p.reset = true
+		case roll(f.ErrorRate):
+			p.status = f.ErrorStatus
+			if p.status == 0 {
+				p.status = http.StatusServiceUnavailable
+			}
+		case !websocket && roll(f.TruncateRate):
+			p.truncate = f.TruncateBytes
+		}
+		if websocket && roll(f.WSDropRate) {
+			p.wsDrop, p.wsAfter = true, time.Duration(f.WSDropAfterMS)*time.Millisecond
+		}
+		if p.delay == 0 && !p.reset && p.status == 0 && p.truncate < 0 && !p.wsDrop {
+			return nil
+		}
+		fs.counts[f.Name]++
+		return p
+	}
+	return nil
+}
+
+func (fs *faultSet) list() []FaultStatus {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	out := make([]FaultStatus, 0, len(fs.rules))
+	for _, f := range fs.rules {
+		out = append(out, FaultStatus{FaultRule: f, Injected: fs.counts[f.Name]})
+	}
+	return out
+}
+
+func (s *Server) Faults() []FaultStatus {
+	return s.faults.list()
+}
+
+func (s *Server) SetFault(rule config.FaultRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	s.faults.mu.Lock()
+	defer s.faults.mu.Unlock()
+	for i := range s.faults.rules {
+		if s.faults.rules[i].Name == rule.Name {
+			s.faults.rules[i] = rule
+			return nil
+		}
+	}
+	s.faults.rules = append(s.faults.rules, rule)
+	return nil
+}
+
+func (s *Server) EnableFault(name string, enabled bool) error {
+	s.faults.mu.Lock()
+	defer s.faults.mu.Unlock()
+	for i := range s.faults.rules {
+		if s.faults.rules[i].Name == name {
+			s.faults.rules[i].Disabled = !enabled
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", errFaultNotFound, name)
+}
+
+func (s *Server) RemoveFault(name string) error {
+	s.faults.mu.Lock()
+	defer s.faults.mu.Unlock()
+	for i := range s.faults.rules {
+		if s.faults.rules[i].Name == name {
+			s.faults.rules = append(s.faults.rules[:i], s.faults.rules[i+1:]...)
+			delete(s.faults.counts, name)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", errFaultNotFound, name)
+}
+
+func routeKey(rt *route) string {
+	if rt.name == "" {
+		return strconv.Itoa(rt.port)
+	}
+	return rt.name
+}
+
+func (s *Server) injectFault(w *responseWriter, r *http.Request, requestID string, rt *route) bool {
+	p := s.faults.plan(routeKey(rt), r.Method, r.URL.Path, isWebSocketRequest(r))
+	if p == nil {
+		return false
+	}
+	w.fault = p
+
+	if err := sleep(r.Context(), p.delay); err != nil {
+		w.err = err
+		return true
+	}
+	if p.reset {
+		panic(http.ErrAbortHandler)
+	}
+	if p.status != 0 {
+		w.Header().Set("X-Httpsify-Fault", p.rule)
+		s.handleError(w, r, requestID, p.status, "Injected fault: "+http.StatusText(p.status),
+			fmt.Sprintf("Fault rule %q is active; disable it on the dashboard or with httpsify fault disable %s", p.rule, p.rule), "")
+		return true
+	}
+	return false
+}
+
+func (s *Server) serveFaultsAPI(w http.ResponseWriter, r *http.Request) {
+	name, action, _ := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/faults"), "/"), "/")
+	var err error
+	switch {
+	case name == "" && r.Method == http.MethodGet:
+		s.writeJSON(w, s.Faults())
+		return
+	case name == "" && r.Method == http.MethodPost:
+		var rule config.FaultRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			s.writeJSONError(w, http.StatusBadRequest, "Invalid fault rule", err.Error(), `{"name": "slow-api", "route": "api", "latency_ms": 500}`)
+			return
+		}
+		err = s.SetFault(rule)
+	case name != "" && r.Method == http.MethodDelete && action == "":
+		err = s.RemoveFault(name)
+	case name != "" && r.Method == http.MethodPost && (action == "enable" || action == "disable"):
+		err = s.EnableFault(name, action == "enable")
+	default:
+		s.writeJSONError(w, http.StatusMethodNotAllowed, "Unsupported fault operation",
+			"GET or POST /api/faults, POST /api/faults/<name>/enable|disable, DELETE /api/faults/<name>", "")
+		return
+	}
+	if errors.Is(err, errFaultNotFound) {
+		s.writeJSONError(w, http.StatusNotFound, err.Error(), "", "")
+		return
+	}
+	if err != nil {
+		s.writeJSONError(w, http.StatusBadRequest, err.Error(), "", "")
+		return
+	}
+	s.writeJSON(w, s.Faults())
+}
+
+func (s *Server) serveFaultToggle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeJSONError(w, http.StatusMethodNotAllowed, "Use POST to toggle a fault rule", "", "")
+		return
+	}
+	if err := s.EnableFault(r.PostFormValue("name"), r.PostFormValue("enabled") == "true"); err != nil {
+		s.writeJSONError(w, http.StatusNotFound, err.Error(), "", "")
+		return
+	}
+	http.Redirect(w, r, "/#fault-section", http.StatusSeeOther)
+}
@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestFaultInjection(t *testing.T) {
+	payload := strings.Repeat("x", 2000)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(payload))
+	}))
+	defer backend.Close()
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{{Name: "shop", Port: port}}
+	cfg.Faults = []config.FaultRule{
+		{Name: "errors", Route: "shop", Path: "/error", ErrorRate: 100, ErrorStatus: 502},
+		{Name: "slow", Route: "shop", Path: "/slow", LatencyMS: 50},
+		{Name: "reset", Path: "/reset", ResetRate: 100},
+		{Name: "cut", Path: "/cut", TruncateRate: 100, TruncateBytes: 10},
+		{Name: "other", Route: "billing", ErrorRate: 100},
+	}
+	s := newTestServer(t, cfg)
+	front := httptest.NewTLSServer(s)
+	defer front.Close()
+	front.Config.ErrorLog = log.New(io.Discard, "", 0)
+
+	get := func(path string) (*http.Response, string, error) {
+		req, _ := http.NewRequest("GET", front.URL+path, nil)
+		req.Host = "shop.localhost"
+		resp, err := front.Client().Do(req)
+		if err != nil {
+			return nil, "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return resp, string(body), err
+	}
+
+	resp, _, err := get("/error")
+	if err != nil || resp.StatusCode != http.StatusBadGateway || resp.Header.Get("X-Httpsify-Fault") != "errors" {
+		t.Errorf("error fault = %v, %v", resp, err)
+	}
+
+	start := time.Now()
+	resp, body, err := get("/slow")
+	if err != nil || resp.StatusCode != http.StatusOK || body != payload || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("latency fault = %v after %v, %v", resp, time.Since(start), err)
+	}
+
+	if _, _, err := get("/reset"); err == nil {
+		t.Error("reset fault returned a response")
+	}
+
+	resp, body, err = get("/cut")
+	if err == nil || len(body) != 10 {
+		t.Errorf("truncate fault = %d bytes, %v; want 10 bytes and a read error", len(body), err)
+	}
+
+	if resp, body, err := get("/untouched"); err != nil || body != payload {
+		t.Errorf("unmatched request = %v, %v", resp, err)
+	}
+
+	if err := s.EnableFault("errors", false); err != nil {
+		t.Fatal(err)
+	}
+	if resp, _, err := get("/error"); err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("disabled fault = %v, %v", resp, err)
+	}
+
+	token, _ := s.DashboardToken()
+	api := func(method, path, body string) (int, []FaultStatus) {
+		req := newLocalRequest(method, "https://localhost"+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		var faults []FaultStatus
+		json.NewDecoder(rr.Body).Decode(&faults)
+		return rr.Code, faults
+	}
+	if code, faults := api("POST", "/api/faults/errors/enable", ""); code != http.StatusOK || faults[0].Disabled || faults[0].Injected != 1 {
+		t.Errorf("enable = %d %+v", code, faults)
+	}
+	if code, faults := api("POST", "/api/faults", `{"name": "teapot", "error_rate": 100}`); code != http.StatusOK || len(faults) != 6 {
+		t.Errorf("add = %d, %d rules", code, len(faults))
+	}
+	if code, _ := api("POST", "/api/faults", `{"name": "noop"}`); code != http.StatusBadRequest {
+		t.Errorf("invalid rule = %d, want %d", code, http.StatusBadRequest)
+	}
+	if code, _ := api("DELETE", "/api/faults/missing", ""); code != http.StatusNotFound {
+		t.Errorf("remove unknown = %d, want %d", code, http.StatusNotFound)
+	}
+	if code, faults := api("DELETE", "/api/faults/teapot", ""); code != http.StatusOK || len(faults) != 5 {
+		t.Errorf("remove = %d, %d rules", code, len(faults))
+	}
+
+	cookie, csrf := loopbackCSRF(s)
+	form := url.Values{"csrf_token": {csrf}, "name": {"slow"}, "enabled": {"false"}}
+	req := newLocalRequest("POST", "https://localhost/faults", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	if rr.Code != http.StatusSeeOther || !s.Faults()[1].Disabled {
+		t.Errorf("dashboard toggle = %d, disabled %v", rr.Code, s.Faults()[1].Disabled)
+	}
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://localhost/", nil))
+	if !strings.Contains(rr.Body.String(), "Fault Injection") || !strings.Contains(rr.Body.String(), "shop/error, 5xx 100%") {
+		t.Error("dashboard does not list fault rules")
+	}
+}
@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func newH2CTransport(base *http.Transport) *http.Transport {
+	tr := base.Clone()
+	tr.Protocols = new(http.Protocols)
+	tr.Protocols.SetUnencryptedHTTP2(true)
+	return tr
+}
+
+func isGRPCContentType(contentType string) bool {
+	return strings.HasPrefix(strings.ToLower(contentType), "application/grpc")
+}
+
+func writeGRPCUnavailable(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Grpc-Status", "14")
+	w.Header().Set("Grpc-Message", message)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) probeH2CService(port int) (ServiceInfo, bool) {
+	info := ServiceInfo{
+		Port:    port,
+		Headers: make(map[string]string),
+	}
+
+	client := &http.Client{
+		Transport: s.probeH2C,
+		Timeout:   250 * time.Millisecond,
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://127.0.0.1:%d/httpsify.Discovery/Probe", port), http.NoBody)
+	if err != nil {
+		return info, false
+	}
+	req.Header.Set("User-Agent", "httpsify-discovery/1.0")
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return info, false
+	}
+	defer resp.Body.Close()
+
+	info.Latency = time.Since(start).Round(time.Millisecond).String()
+	info.StatusCode = resp.StatusCode
+	info.Server = resp.Header.Get("Server")
+	info.ContentType = resp.Header.Get("Content-Type")
+	info.IsWeb = true
+	for k, v := range resp.Header {
+		if len(v) > 0 {
+			info.Headers[k] = v[0]
+		}
+	}
+
+	info.Protocol = "h2c"
+	if isGRPCContentType(info.ContentType) || resp.Header.Get("Grpc-Status") != "" || resp.Trailer.Get("Grpc-Status") != "" {
+		info.Protocol = "gRPC"
+	}
+
+	return info, true
+}
@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestGRPCOverH2C(t *testing.T) {
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			t.Errorf("backend proto = %s, want HTTP/2", r.Proto)
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.WriteHeader(http.StatusOK)
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	backend.Config.Protocols = new(http.Protocols)
+	backend.Config.Protocols.SetUnencryptedHTTP2(true)
+	backend.Start()
+	defer backend.Close()
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{{Name: "grpc", Port: port, Scheme: "h2c"}}
+	s := newTestServer(t, cfg)
+
+	info := s.probeService(port)
+	if info.Protocol != "gRPC" || !info.IsWeb {
+		t.Fatalf("probeService() = %+v, want gRPC service", info)
+	}
+
+	for _, host := range []string{"grpc.localhost", fmt.Sprintf("%d.localhost", port)} {
+		req := newLocalRequest("POST", "https://"+host+"/demo.Greeter/SayHello", strings.NewReader(""))
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("TE", "trailers")
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+
+		resp := rr.Result()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: status = %d, want %d", host, resp.StatusCode, http.StatusOK)
+		}
+		if got := resp.Trailer.Get("Grpc-Status"); got != "0" {
+			t.Errorf("%s: Grpc-Status trailer = %q, want %q", host, got, "0")
+		}
+	}
+}
+
+func TestPortSchemeDetection(t *testing.T) {
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secure"))
+	}))
+	defer secure.Close()
+	secure.Config.ErrorLog = log.New(io.Discard, "", 0)
+	grpc := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	grpc.Config.Protocols = new(http.Protocols)
+	grpc.Config.Protocols.SetUnencryptedHTTP2(true)
+	grpc.Start()
+	defer grpc.Close()
+
+	s := newTestServer(t, config.DefaultConfig())
+	send := func(port int, contentType string) *httptest.ResponseRecorder {
+		req := newLocalRequest("POST", fmt.Sprintf("https://%d.localhost/", port), strings.NewReader(""))
+		req.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := send(secure.Listener.Addr().(*net.TCPAddr).Port, "text/plain"); rr.Code != http.StatusOK || rr.Body.String() != "secure" {
+		t.Errorf("TLS port = %d %q, want it detected on first use", rr.Code, rr.Body.String())
+	}
+
+	port := grpc.Listener.Addr().(*net.TCPAddr).Port
+	send(port, "application/grpc")
+	if rr := send(port, "application/grpc"); rr.Code != http.StatusOK || rr.Header().Get("Grpc-Status") != "0" {
+		t.Errorf("h2c port = %d, Grpc-Status %q, want it re-probed after the failed request", rr.Code, rr.Header().Get("Grpc-Status"))
+	}
+}
+
+func TestGRPCUpstreamUnavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{{Name: "grpc", Port: port, Scheme: "h2c"}}
+	s := newTestServer(t, cfg)
+
+	req := newLocalRequest("POST", "https://grpc.localhost/demo.Greeter/SayHello", nil)
+	req.Header.Set("Content-Type", "application/grpc")
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || rr.Header().Get("Grpc-Status") != "14" {
+		t.Errorf("status = %d, Grpc-Status = %q, want 200 with status 14", rr.Code, rr.Header().Get("Grpc-Status"))
+	}
+}
@@ -0,0 +1,336 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/version"
+)
+
+const harImportLimit = 64 << 20
+
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ID              string      `json:"_id,omitempty"`
+	Route           string      `json:"_route,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+}
+
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"_encoding,omitempty"`
+}
+
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+func (s *Server) ExportHAR(f ExchangeFilter) *HAR {
+	har := &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "httpsify", Version: version.Get().Version},
+		Entries: []HAREntry{},
+	}}
+	if s.inspector == nil {
+		return har
+	}
+	exchanges := s.inspector.list(f)
+	for i := len(exchanges) - 1; i >= 0; i-- {
+		har.Log.Entries = append(har.Log.Entries, harEntry(exchanges[i]))
+	}
+	return har
+}
+
+func harEntry(e *Exchange) HAREntry {
+	entry := HAREntry{
+		StartedDateTime: e.Time,
+		Time:            e.Timings.Total,
+		ID:              e.ID,
+		Route:           e.Route,
+		Error:           e.Error,
+		Request: HARRequest{
+			Method:      e.Method,
+			URL:         e.URL,
+			HTTPVersion: e.Proto,
+			Cookies:     []HARCookie{},
+			Headers:     harHeaders(e.RequestHeaders),
+			QueryString: []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    e.RequestBody.Size,
+		},
+		Response: HARResponse{
+			Status:      e.Status,
+			StatusText:  http.StatusText(e.Status),
+			HTTPVersion: e.Proto,
+			Cookies:     []HARCookie{},
+			Headers:     harHeaders(e.ResponseHeaders),
+			Content: HARContent{
+				Size:     e.ResponseBody.Size,
+				MimeType: e.ResponseHeaders.Get("Content-Type"),
+				Text:     e.ResponseBody.Text,
+			},
+			RedirectURL: e.ResponseHeaders.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    e.ResponseBody.Size,
+		},
+		// The HAR spec uses -1 for phases that were not measured.
+		Timings: HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+	}
+
+	for _, c := range (&http.Request{Header: e.RequestHeaders}).Cookies() {
+		entry.Request.Cookies = append(entry.Request.Cookies, HARCookie{Name: c.Name, Value: c.Value})
+	}
+	for _, c := range (&http.Response{Header: e.ResponseHeaders}).Cookies() {
+		entry.Response.Cookies = append(entry.Response.Cookies, HARCookie{
+			Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain, HTTPOnly: c.HttpOnly, Secure: c.Secure,
+		})
+	}
+	if u, err := url.Parse(e.URL); err == nil {
+		for name, values := range u.Query() {
+			for _, v := range values {
+				entry.Request.QueryString = append(entry.Request.QueryString, HARNameValue{name, v})
+			}
+		}
+	}
+
+	if e.RequestBody.Size > 0 {
+		post := &HARPostData{MimeType: e.RequestHeaders.Get("Content-Type"), Text: e.RequestBody.Text}
+		if e.RequestBody.Binary {
+			post.Text, post.Encoding = e.RequestBody.Base64, "base64"
+		}
+		entry.Request.PostData = post
+	}
+	if e.ResponseBody.Binary {
+		entry.Response.Content.Text = e.ResponseBody.Base64
+		entry.Response.Content.Encoding = "base64"
+	}
+	if e.ResponseBody.Truncated {
+		entry.Response.Content.Comment = "truncated by httpsify --inspect-body"
+	}
+
+	t := e.Timings
+	if t.Connect > 0 {
+		entry.Timings.Connect = t.Connect
+	}
+	if t.FirstByte > 0 {
+		entry.Timings.Wait = max(t.FirstByte-t.Connect, 0)
+		entry.Timings.Receive = max(t.Total-t.FirstByte, 0)
+	} else {
+		entry.Timings.Wait = max(t.Total-t.Connect, 0)
+	}
+	return entry
+}
+
+func harHeaders(h http.Header) []HARNameValue {
+	out := []HARNameValue{}
+	for _, p := range headerPairs(h) {
+		out = append(out, HARNameValue{p[0], p[1]})
+	}
+	return out
+}
+
+func (s *Server) ImportHAR(r io.Reader) ([]string, error) {
+	if s.inspector == nil {
+		return nil, errors.New("traffic inspector is disabled")
+	}
+	var har HAR
+	if err := json.NewDecoder(io.LimitReader(r, harImportLimit)).Decode(&har); err != nil {
+		return nil, fmt.Errorf("invalid HAR file: %w", err)
+	}
+	if len(har.Log.Entries) == 0 {
+		return nil, errors.New("HAR file has no entries")
+	}
+
+	ids := make([]string, 0, len(har.Log.Entries))
+	for _, entry := range har.Log.Entries {
+		e := exchangeFromHAR(entry, s.inspector.bodyLimit)
+		e.ID = s.generateRequestID()
+		s.inspector.add(e)
+		ids = append(ids, e.ID)
+	}
+	return ids, nil
+}
+
+func exchangeFromHAR(entry HAREntry, limit int) *Exchange {
+	e := &Exchange{
+		Time:            entry.StartedDateTime,
+		Route:           entry.Route,
+		Method:          entry.Request.Method,
+		URL:             entry.Request.URL,
+		Proto:           entry.Request.HTTPVersion,
+		RequestHeaders:  fromHARHeaders(entry.Request.Headers),
+		Status:          entry.Response.Status,
+		ResponseHeaders: fromHARHeaders(entry.Response.Headers),
+		Error:           entry.Error,
+		Imported:        true,
+	}
+
+	if post := entry.Request.PostData; post != nil {
+		if e.RequestHeaders.Get("Content-Type") == "" && post.MimeType != "" {
+			e.RequestHeaders.Set("Content-Type", post.MimeType)
+		}
+		e.RequestBody = harBody(post.Text, post.Encoding, limit, e.RequestHeaders)
+	}
+	e.ResponseBody = harBody(entry.Response.Content.Text, entry.Response.Content.Encoding, limit, e.ResponseHeaders)
+
+	t := entry.Timings
+	e.Timings.Total = entry.Time
+	if t.Connect > 0 {
+		e.Timings.Connect = t.Connect
+	}
+	for _, phase := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait} {
+		if phase > 0 {
+			e.Timings.FirstByte += phase
+		}
+	}
+	return e
+}
+
+func fromHARHeaders(pairs []HARNameValue) http.Header {
+	h := http.Header{}
+	for _, p := range pairs {
+		if strings.HasPrefix(p.Name, ":") || strings.EqualFold(p.Name, "Host") {
+			continue
+		}
+		h.Add(p.Name, p.Value)
+	}
+	return h
+}
+
+func harBody(text, encoding string, limit int, h http.Header) CapturedBody {
+	data := []byte(text)
+	if encoding == "base64" {
+		if decoded, err := base64.StdEncoding.DecodeString(text); err == nil {
+			data = decoded
+		}
+	}
+	// Browsers store decoded bodies, so the encoding header no longer applies.
+	h = h.Clone()
+	h.Del("Content-Encoding")
+	return capturedBody(data[:min(limit, len(data))], int64(len(data)), limit, h)
+}
+
+func (s *Server) serveHARAPI(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		f, err := filterFromQuery(r.URL.Query())
+		if err != nil {
+			s.writeJSONError(w, http.StatusBadRequest, err.Error(), "", "/api/har?route=shop&since=15m")
+			return
+		}
+		name := "httpsify"
+		if f.Route != "" {
+			name += "-" + f.Route
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.har"`, name, time.Now().Format("20060102-150405")))
+		s.writeJSON(w, s.ExportHAR(f))
+	case http.MethodPost:
+		ids, err := s.ImportHAR(r.Body)
+		if err != nil {
+			s.writeJSONError(w, http.StatusBadRequest, "HAR import failed", err.Error(), "")
+			return
+		}
+		s.writeJSON(w, map[string]any{"imported": len(ids), "ids": ids})
+	default:
+		s.writeJSONError(w, http.StatusMethodNotAllowed, "Use GET to export or POST to import a HAR file", "", "")
+	}
+}
+
+func (s *Server) serveHARImportForm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeJSONError(w, http.StatusMethodNotAllowed, "Use POST to import a HAR file", "", "")
+		return
+	}
+	file, _, err := r.FormFile("har")
+	if err != nil {
+		s.writeJSONError(w, http.StatusBadRequest, "HAR import failed", "Choose a .har file to upload", "")
+		return
+	}
+	defer file.Close()
+	ids, err := s.ImportHAR(file)
+	if err != nil {
+		s.writeJSONError(w, http.StatusBadRequest, "HAR import failed", err.Error(), "")
+		return
+	}
+	http.Redirect(w, r, "/inspector?id="+url.QueryEscape(ids[len(ids)-1]), http.StatusSeeOther)
+}
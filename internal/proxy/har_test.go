@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+	"github.com/imcanugur/httpsify/internal/logging"
+)
+
+func TestHAR(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/image" {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n\x1a\n"))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "abc", Path: "/", HttpOnly: true})
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"echo":%q}`, body)
+	}))
+	defer backend.Close()
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{{Name: "shop", Port: port}, {Name: "admin", Port: port}}
+	s := newTestServer(t, cfg)
+
+	send := func(method, target, body string) {
+		req := newLocalRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Cookie", "theme=dark")
+		s.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	send("POST", "https://shop.localhost/orders?page=2", `{"id":1}`)
+	send("GET", "https://shop.localhost/image", "")
+	send("GET", "https://admin.localhost/", "")
+
+	har := s.ExportHAR(ExchangeFilter{Route: "shop"})
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 2 {
+		t.Fatalf("export = version %s with %d entries, want 1.2 with 2", har.Log.Version, len(har.Log.Entries))
+	}
+	post, image := har.Log.Entries[0], har.Log.Entries[1]
+	if post.Request.Method != "POST" || post.Request.PostData == nil || post.Request.PostData.Text != `{"id":1}` {
+		t.Errorf("entries out of order or missing post data: %+v", post.Request)
+	}
+	if !reflect.DeepEqual(post.Request.QueryString, []HARNameValue{{"page", "2"}}) {
+		t.Errorf("queryString = %v", post.Request.QueryString)
+	}
+	if len(post.Request.Cookies) != 1 || len(post.Response.Cookies) != 1 || !post.Response.Cookies[0].HTTPOnly {
+		t.Errorf("cookies = %v / %v", post.Request.Cookies, post.Response.Cookies)
+	}
+	if post.Response.Content.Text != `{"echo":"{\"id\":1}"}` || post.Response.StatusText != "OK" {
+		t.Errorf("response = %+v", post.Response)
+	}
+	if image.Response.Content.Encoding != "base64" || image.Response.Content.Text != base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n")) {
+		t.Errorf("binary content = %+v", image.Response.Content)
+	}
+	for _, e := range har.Log.Entries {
+		tm := e.Timings
+		sum := max(tm.Connect, 0) + tm.Send + tm.Wait + tm.Receive
+		if tm.Blocked != -1 || tm.DNS != -1 || tm.SSL != -1 || math.Abs(sum-e.Time) > 0.01 || e.Time <= 0 {
+			t.Errorf("timings %+v do not add up to time %v", tm, e.Time)
+		}
+	}
+
+	windows := []struct {
+		query string
+		want  int
+	}{
+		{"since=1h", 3},
+		{"until=2000-01-01T00:00:00Z", 0},
+		{"route=admin&since=" + url.QueryEscape(time.Now().Add(-time.Minute).Format(time.RFC3339)), 1},
+	}
+	for _, tt := range windows {
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, newLocalRequest("GET", "https://localhost/api/har?"+tt.query, nil))
+		var got HAR
+		if err := json.NewDecoder(rr.Body).Decode(&got); err != nil || len(got.Log.Entries) != tt.want {
+			t.Errorf("/api/har?%s = %d entries, %v; want %d", tt.query, len(got.Log.Entries), err, tt.want)
+		}
+		if !strings.Contains(rr.Header().Get("Content-Disposition"), ".har") {
+			t.Errorf("/api/har?%s is not served as a download", tt.query)
+		}
+	}
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://localhost/api/har?since=yesterday", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid since = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	exported, _ := json.Marshal(har)
+	other := NewServer(cfg, logging.NewLogger(false, false))
+	ids, err := other.ImportHAR(bytes.NewReader(exported))
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("ImportHAR() = %v, %v", ids, err)
+	}
+	imported := other.inspector.get(ids[0])
+	if !imported.Imported || imported.Route != "shop" || imported.RequestBody.Text != `{"id":1}` || imported.Status != http.StatusOK {
+		t.Errorf("imported exchange = %+v", imported)
+	}
+	if imported.Timings.Total != post.Time || imported.Timings.Connect != max(post.Timings.Connect, 0) {
+		t.Errorf("imported timings = %+v, want total %v", imported.Timings, post.Time)
+	}
+	if img := other.inspector.get(ids[1]); !img.ResponseBody.Binary || img.ResponseBody.Size != 8 {
+		t.Errorf("imported binary body = %+v", img.ResponseBody)
+	}
+
+	browser := `{"log":{"version":"1.2","entries":[{"startedDateTime":"2026-01-02T03:04:05.678Z","time":42,
+		"request":{"method":"PUT","url":"https://api.example.com/items/1","httpVersion":"h2",
+			"headers":[{"name":":authority","value":"api.example.com"},{"name":"x-trace","value":"7"}],
+			"postData":{"mimeType":"application/json","text":"{\"a\":1}"}},
+		"response":{"status":204,"headers":[{"name":"content-encoding","value":"gzip"}],"content":{"size":0,"mimeType":""}},
+		"timings":{"blocked":1,"dns":-1,"connect":5,"send":1,"wait":20,"receive":15}}]}}`
+	token, _ := other.DashboardToken()
+	req := newLocalRequest("POST", "https://localhost/api/har", strings.NewReader(browser))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	other.ServeHTTP(rr, req)
+	var resp struct{ IDs []string }
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil || len(resp.IDs) != 1 {
+		t.Fatalf("POST /api/har = %d %v", rr.Code, err)
+	}
+	e := other.inspector.get(resp.IDs[0])
+	if e.RequestHeaders.Get(":authority") != "" || e.RequestHeaders.Get("X-Trace") != "7" || e.Timings.FirstByte != 27 || e.Timings.Connect != 5 {
+		t.Errorf("browser entry = headers %v timings %+v", e.RequestHeaders, e.Timings)
+	}
+	result, err := other.Replay(context.Background(), e.ID, ReplayRequest{Route: "shop"})
+	if err != nil || result.Body.Text != `{"echo":"{\"a\":1}"}` {
+		t.Errorf("replay of imported entry = %+v, %v", result, err)
+	}
+
+	if _, err := other.ImportHAR(strings.NewReader(`{"log":{"entries":[]}}`)); err == nil {
+		t.Error("ImportHAR() accepted a HAR without entries")
+	}
+}
@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/imcanugur/httpsify/internal/config"
+	"github.com/imcanugur/httpsify/internal/logging"
+)
+
+func (rt *route) headerVars(req *http.Request, publicHost string) *strings.Replacer {
+	clientIP, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		clientIP = req.RemoteAddr
+	}
+	requestID, _ := req.Context().Value(logging.RequestIDKey).(string)
+
+	return strings.NewReplacer(
+		"{client_ip}", clientIP,
+		"{request_id}", requestID,
+		"{route}", rt.label(),
+		"{host}", publicHost,
+		"{method}", req.Method,
+		"{upstream}", rt.target.Host,
+	)
+}
+
+func applyHeaderOps(h http.Header, ops config.HeaderOps, vars *strings.Replacer) {
+	for _, name := range ops.Remove {
+		prefix, wildcard := strings.CutSuffix(name, "*")
+		if !wildcard {
+			h.Del(name)
+			continue
+		}
+		prefix = http.CanonicalHeaderKey(prefix)
+		for key := range h {
+			if strings.HasPrefix(key, prefix) {
+				delete(h, key)
+			}
+		}
+	}
+	for name, value := range ops.Set {
+		h.Set(name, vars.Replace(value))
+	}
+	for name, value := range ops.Append {
+		h.Add(name, vars.Replace(value))
+	}
+}
@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestHeaderRewriteRules(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Powered-By", "Express")
+		w.Header().Set("X-Debug-Query", "12ms")
+		w.Header().Set("X-Debug-Cache", "miss")
+		w.Header().Set("X-Upstream-Env", r.Header.Get("X-Env"))
+		w.Header().Set("X-Upstream-Client", r.Header.Get("X-Real-IP"))
+		w.Header().Set("X-Upstream-Route", r.Header.Get("X-Route"))
+		w.Header().Set("X-Upstream-Cookie", r.Header.Get("Cookie"))
+	}))
+	defer backend.Close()
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{{
+		Name: "app",
+		Port: port,
+		Headers: &config.HeaderRules{
+			Request: config.HeaderOps{
+				Set:    map[string]string{"X-Env": "staging", "X-Real-IP": "{client_ip}", "X-Route": "{route}"},
+				Remove: []string{"Cookie"},
+			},
+			Response: config.HeaderOps{
+				Set:    map[string]string{"Strict-Transport-Security": "max-age=63072000"},
+				Append: map[string]string{"Via": "1.1 httpsify ({request_id})"},
+				Remove: []string{"X-Powered-By", "X-Debug-*"},
+			},
+		},
+	}}
+	cfg.AllowClients = []string{"192.0.2.0/24"}
+	s := newTestServer(t, cfg)
+
+	req := newLocalRequest("GET", "https://app.localhost/", nil)
+	req.RemoteAddr = "192.0.2.10:5555"
+	req.Header.Set("Cookie", "session=secret")
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+
+	h := rr.Header()
+	want := map[string]string{
+		"X-Upstream-Env":            "staging",
+		"X-Upstream-Client":         "192.0.2.10",
+		"X-Upstream-Route":          "app",
+		"X-Upstream-Cookie":         "",
+		"X-Powered-By":              "",
+		"X-Debug-Query":             "",
+		"X-Debug-Cache":             "",
+		"Strict-Transport-Security": "max-age=63072000",
+		"Via":                       "1.1 httpsify (" + h.Get("X-Request-ID") + ")",
+	}
+	for name, value := range want {
+		if got := h.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/logging"
+)
+
+var (
+	errBodyDetached  = errors.New("request body is being replayed")
+	errStreamingBody = errors.New("request body has no length")
+	errLargeBody     = errors.New("request body is over 1MB")
+	errH2CBody       = errors.New("h2c request bodies are streamed")
+)
+
+const (
+	maxHoldBody    = 1 << 20
+	holdBackoffMin = 50 * time.Millisecond
+	holdBackoffMax = time.Second
+)
+
+type heldCounts struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (h *heldCounts) add(key string, delta int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.counts == nil {
+		h.counts = make(map[string]int)
+	}
+	h.counts[key] += delta
+	if h.counts[key] <= 0 {
+		delete(h.counts, key)
+	}
+}
+
+func (s *Server) HeldRequests() map[string]int {
+	s.held.mu.Lock()
+	defer s.held.mu.Unlock()
+	out := make(map[string]int, len(s.held.counts))
+	for k, v := range s.held.counts {
+		out[k] = v
+	}
+	return out
+}
+
+func backendDown(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, fs.ErrNotExist)
+}
+
+func connDropped(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func (s *Server) hold(ctx context.Context, rt *route, retry func(error) bool, attempt func() error) error {
+	err := attempt()
+	if err == nil || rt.hold <= 0 || !retry(err) {
+		return err
+	}
+
+	key := routeKey(rt)
+	s.held.add(key, 1)
+	defer s.held.add(key, -1)
+	requestID, _ := ctx.Value(logging.RequestIDKey).(string)
+	s.logger.Debug("holding request while backend restarts", "request_id", requestID, "route", key, "error", err.Error())
+
+	deadline := time.Now().Add(rt.hold)
+	for backoff := holdBackoffMin; ; backoff = min(backoff*2, holdBackoffMax) {
+		wait := min(backoff, time.Until(deadline))
+		if wait <= 0 || sleep(ctx, wait) != nil {
+			return err
+		}
+		if err = attempt(); err == nil || !retry(err) {
+			return err
+		}
+	}
+}
+
+type holdTransport struct {
+	s    *Server
+	rt   *route
+	next http.RoundTripper
+}
+
+func (h *holdTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req, replayable := replayableBody(req, h.rt)
+
+	var resp *http.Response
+	attempts := 0
+	err := h.s.hold(req.Context(), h.rt, func(err error) bool {
+		return replayable && (backendDown(err) || connDropped(err) && isIdempotent(req.Method))
+	}, func() error {
+		out := req
+		if attempts++; attempts > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			out = req.Clone(req.Context())
+			out.Body = body
+		}
+		var err error
+		resp, err = h.next.RoundTrip(out)
+		return err
+	})
+	return resp, err
+}
+
+func unreplayable(req *http.Request, rt *route) error {
+	switch {
+	case req.Body == nil || req.Body == http.NoBody || req.GetBody != nil:
+		return nil
+	case rt.scheme == "h2c":
+		return errH2CBody
+	case req.ContentLength <= 0:
+		return errStreamingBody
+	case req.ContentLength > maxHoldBody:
+		return errLargeBody
+	}
+	return nil
+}
+
+func replayableBody(req *http.Request, rt *route) (*http.Request, bool) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return req, true
+	}
+	if unreplayable(req, rt) != nil {
+		return req, false
+	}
+	body := &replayBody{body: req.Body, size: req.ContentLength}
+	out := req.Clone(req.Context())
+	out.Body = body
+	out.GetBody = body.rewind
+	return out, true
+}
+
+type replayBody struct {
+	mu       sync.Mutex
+	body     io.ReadCloser
+	size     int64
+	data     []byte
+	detached bool
+}
+
+func (b *replayBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.detached {
+		return 0, errBodyDetached
+	}
+	n, err := b.body.Read(p)
+	b.data = append(b.data, p[:n]...)
+	return n, err
+}
+
+func (b *replayBody) Close() error {
+	// The server closes the client body; a retry may still need the rest.
+	return nil
+}
+
+func (b *replayBody) recorded() ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.data, int64(len(b.data)) == b.size
+}
+
+func (b *replayBody) rewind() (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.detached {
+		b.detached = true
+		rest, err := io.ReadAll(io.LimitReader(b.body, b.size-int64(len(b.data))))
+		b.data = append(b.data, rest...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if int64(len(b.data)) != b.size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return io.NopCloser(bytes.NewReader(b.data)), nil
+}
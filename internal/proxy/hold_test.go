@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestHoldWhileBackendRestarts(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.HoldTimeout = 5
+	cfg.Routes = []config.Route{{Name: "api", Port: port}, {Name: "gone", Port: port, HoldTimeout: 1}}
+	s := newTestServer(t, cfg)
+
+	type result struct {
+		code int
+		body string
+	}
+	send := func(method, host, body string) <-chan result {
+		done := make(chan result, 1)
+		go func() {
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, newLocalRequest(method, "https://"+host+"/", strings.NewReader(body)))
+			done <- result{rr.Code, rr.Body.String()}
+		}()
+		return done
+	}
+	waitHeld := func(n int) {
+		deadline := time.Now().Add(2 * time.Second)
+		for s.HeldRequests()["api"] != n {
+			if time.Now().After(deadline) {
+				t.Fatalf("held = %v, want %d on api", s.HeldRequests(), n)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	get := send("GET", "api.localhost", "")
+	post := send("POST", "api.localhost", "order=1")
+	waitHeld(2)
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://localhost/", nil))
+	if !strings.Contains(rr.Body.String(), "Waiting for Restart") || !strings.Contains(rr.Body.String(), "2 held") {
+		t.Error("dashboard does not show held requests")
+	}
+
+	large := send("POST", "api.localhost", strings.Repeat("x", maxHoldBody+1))
+	if res := <-large; res.code != http.StatusBadGateway {
+		t.Errorf("large body = %d, want %d without holding", res.code, http.StatusBadGateway)
+	}
+
+	ln, err = net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		t.Skipf("port %d was taken: %v", port, err)
+	}
+	backend := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s", r.Method, body)
+	})}
+	go backend.Serve(ln)
+	defer backend.Close()
+
+	if res := <-get; res.code != http.StatusOK || res.body != "GET " {
+		t.Errorf("held GET = %d %q", res.code, res.body)
+	}
+	if res := <-post; res.code != http.StatusOK || res.body != "POST order=1" {
+		t.Errorf("held POST = %d %q", res.code, res.body)
+	}
+	waitHeld(0)
+
+	backend.Close()
+	start := time.Now()
+	res := <-send("GET", "gone.localhost", "")
+	if res.code != http.StatusBadGateway || !strings.Contains(res.body, "waited 1s") || time.Since(start) < time.Second {
+		t.Errorf("expired hold = %d %q after %v", res.code, res.body, time.Since(start))
+	}
+}
+
+func TestReplayableBody(t *testing.T) {
+	rt := &route{}
+	req := newLocalRequest("POST", "https://api.localhost/", strings.NewReader("order=1"))
+	out, ok := replayableBody(req, rt)
+	if !ok {
+		t.Fatal("small body with known length is not replayable")
+	}
+	buf := make([]byte, 3)
+	if n, _ := out.Body.Read(buf); string(buf[:n]) != "ord" {
+		t.Fatalf("first attempt read %q", buf[:n])
+	}
+	body, err := out.GetBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(body); string(data) != "order=1" {
+		t.Errorf("rewound body = %q", data)
+	}
+	if _, err := out.Body.Read(buf); !errors.Is(err, errBodyDetached) {
+		t.Errorf("earlier attempt still reads the body: %v", err)
+	}
+
+	streaming := newLocalRequest("POST", "https://api.localhost/", strings.NewReader("chunk"))
+	streaming.ContentLength = -1
+	if out, ok := replayableBody(streaming, rt); ok || out != streaming {
+		t.Error("unknown-length body was wrapped")
+	}
+	if _, ok := replayableBody(newLocalRequest("POST", "https://api.localhost/", strings.NewReader("x")), &route{scheme: "h2c"}); ok {
+		t.Error("h2c body was wrapped")
+	}
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+var defaultHosts = newHostMatcher(config.DefaultSuffixes, config.DefaultHostTemplate)
+
+type hostMatcher struct {
+	port *regexp.Regexp
+	root *regexp.Regexp
+	name *regexp.Regexp
+}
+
+func newHostMatcher(suffixes []string, template string) *hostMatcher {
+	quoted := make([]string, len(suffixes))
+	for i, suffix := range suffixes {
+		quoted[i] = regexp.QuoteMeta(suffix)
+	}
+	suffixGroup := "(" + strings.Join(quoted, "|") + ")"
+	const portSuffix = `(?::\d+)?$`
+
+	var portExpr strings.Builder
+	portExpr.WriteString("^")
+	rest := template
+	for rest != "" {
+		start := strings.Index(rest, "{")
+		end := strings.Index(rest, "}")
+		if start < 0 || end < start {
+			portExpr.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+		portExpr.WriteString(regexp.QuoteMeta(rest[:start]))
+		switch rest[start : end+1] {
+		case "{port}":
+			portExpr.WriteString(`(?P<port>\d+)`)
+		case "{name}":
+			portExpr.WriteString(`[a-z0-9]+(?:-[a-z0-9]+)*`)
+		case "{suffix}":
+			portExpr.WriteString(suffixGroup)
+		}
+		rest = rest[end+1:]
+	}
+	portExpr.WriteString(portSuffix)
+
+	return &hostMatcher{
+		port: regexp.MustCompile(portExpr.String()),
+		root: regexp.MustCompile("^" + suffixGroup + portSuffix),
+		name: regexp.MustCompile(`^([a-z0-9-]+)\.` + suffixGroup + portSuffix),
+	}
+}
+
+func (m *hostMatcher) matchPort(host string) (string, bool) {
+	matches := m.port.FindStringSubmatch(host)
+	if matches == nil {
+		return "", false
+	}
+	return matches[m.port.SubexpIndex("port")], true
+}
+
+func (s *Server) hostMatcher() *hostMatcher {
+	if s.hosts == nil {
+		return defaultHosts
+	}
+	return s.hosts
+}
+
+func (s *Server) portURL(port int, name string) string {
+	return "https://" + s.cfg.PortHost(port, name)
+}
+
+func (s *Server) hostFormat() string {
+	return "https://" + strings.NewReplacer("{port}", "<port>", "{name}", "<name>", "{suffix}", s.cfg.PrimarySuffix()).Replace(s.cfg.Template())
+}
+
+func (s *Server) routeURL(rt config.Route) string {
+	return "https://" + s.cfg.RouteHost(rt.Name) + rt.Path
+}
@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestCustomHostTemplate(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Suffixes = []string{"test", "dev.corp.internal"}
+	cfg.HostTemplate = "{name}-{port}.{suffix}"
+	cfg.Routes = []config.Route{{Name: "billing", Port: 8000}}
+	s := newTestServer(t, cfg)
+
+	tests := []struct {
+		host      string
+		wantPort  int
+		wantRoute string
+		wantErr   bool
+	}{
+		{"shop-3000.test", 3000, "", false},
+		{"my-shop-3000.dev.corp.internal:443", 3000, "", false},
+		{"billing.test", 8000, "billing", false},
+		{"3000.test", 0, "", true},
+		{"shop-3000.localhost", 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			rt, err := s.resolveRoute(tt.host, "/")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveRoute(%q) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+			}
+			if err == nil && (rt.port != tt.wantPort || rt.label() != tt.wantRoute) {
+				t.Errorf("resolveRoute(%q) = %d, %q, want %d, %q", tt.host, rt.port, rt.label(), tt.wantPort, tt.wantRoute)
+			}
+		})
+	}
+
+	if !s.isRootHost("dev.corp.internal") || s.isRootHost("localhost") {
+		t.Error("isRootHost() does not follow configured suffixes")
+	}
+}
@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+type publicHostKey struct{}
+
+func withPublicHost(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), publicHostKey{}, r.Host))
+}
+
+func requestPublicHost(req *http.Request) string {
+	if host, ok := req.Context().Value(publicHostKey{}).(string); ok {
+		return host
+	}
+	return req.Host
+}
+
+func identityHost(rc config.Route, target string) string {
+	switch rc.HostHeader {
+	case "", "public":
+		return ""
+	case "upstream":
+		if rc.Socket != "" {
+			return "localhost"
+		}
+		if config.IsLoopbackHost(rc.UpstreamHost()) {
+			return net.JoinHostPort("localhost", strconv.Itoa(rc.Port))
+		}
+		return target
+	}
+	return rc.HostHeader
+}
+
+func publicOrigin(req *http.Request, publicHost string) string {
+	if isPlainHTTP(req) {
+		return "http://" + publicHost
+	}
+	return "https://" + publicHost
+}
+
+func (rt *route) upstreamOrigin() string {
+	return rt.target.Scheme + "://" + rt.identity
+}
+
+func (rt *route) applyIdentity(req *http.Request, publicHost string) {
+	public, upstream := publicOrigin(req, publicHost), rt.upstreamOrigin()
+	req.Host = rt.identity
+
+	if req.Header.Get("Origin") == public {
+		req.Header.Set("Origin", upstream)
+	}
+	if ref := req.Header.Get("Referer"); hasOrigin(ref, public) {
+		req.Header.Set("Referer", upstream+ref[len(public):])
+	}
+}
+
+func (rt *route) restoreIdentity(resp *http.Response, publicHost string) {
+	if resp.Header.Get("Access-Control-Allow-Origin") == rt.upstreamOrigin() {
+		resp.Header.Set("Access-Control-Allow-Origin", publicOrigin(resp.Request, publicHost))
+	}
+}
+
+func hasOrigin(url, origin string) bool {
+	if !strings.HasPrefix(url, origin) {
+		return false
+	}
+	rest := url[len(origin):]
+	return rest == "" || rest[0] == '/' || rest[0] == '?' || rest[0] == '#'
+}
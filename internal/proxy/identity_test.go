@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestUpstreamHostIdentity(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen-Host", r.Host)
+		w.Header().Set("X-Seen-Origin", r.Header.Get("Origin"))
+		w.Header().Set("X-Seen-Referer", r.Header.Get("Referer"))
+		w.Header().Set("X-Seen-Forwarded-Host", r.Header.Get("X-Forwarded-Host"))
+		w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+		w.Header().Set("Location", "http://"+r.Host+"/next")
+	}))
+	defer backend.Close()
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{
+		{Name: "public", Port: port},
+		{Name: "vite", Port: port, HostHeader: "upstream"},
+		{Name: "django", Port: port, HostHeader: "myapp.test"},
+	}
+	s := newTestServer(t, cfg)
+
+	tests := []struct {
+		route    string
+		wantHost string
+	}{
+		{"public", "public.localhost"},
+		{"vite", fmt.Sprintf("localhost:%d", port)},
+		{"django", "myapp.test"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			public := "https://" + tt.route + ".localhost"
+			req := newLocalRequest("POST", public+"/form", nil)
+			req.Header.Set("Origin", public)
+			req.Header.Set("Referer", public+"/form?step=1")
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, req)
+
+			h := rr.Header()
+			want := map[string]string{
+				"X-Seen-Host":                 tt.wantHost,
+				"X-Seen-Origin":               "http://" + tt.wantHost,
+				"X-Seen-Referer":              "http://" + tt.wantHost + "/form?step=1",
+				"X-Seen-Forwarded-Host":       tt.route + ".localhost",
+				"Access-Control-Allow-Origin": public,
+				"Location":                    public + "/next",
+			}
+			if tt.route == "public" {
+				want["X-Seen-Origin"] = public
+				want["X-Seen-Referer"] = public + "/form?step=1"
+				want["Location"] = "http://public.localhost/next"
+			}
+			for name, value := range want {
+				if got := h.Get(name); got != value {
+					t.Errorf("%s = %q, want %q", name, got, value)
+				}
+			}
+		})
+	}
+}
+
+func TestWebSocketIdentity(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	seen := make(chan *http.Request, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		seen <- req
+		io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+	}()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{{
+		Name:       "vite",
+		Port:       port,
+		HostHeader: "upstream",
+		Headers:    &config.HeaderRules{Request: config.HeaderOps{Set: map[string]string{"X-Env": "dev"}}},
+	}}
+	s := newTestServer(t, cfg)
+	front := httptest.NewTLSServer(s)
+	defer front.Close()
+
+	conn, err := tls.Dial("tcp", front.Listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET /@vite HTTP/1.1\r\nHost: vite.localhost\r\nOrigin: https://vite.localhost\r\n"+
+		"Connection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("upgrade = %v, %v", resp, err)
+	}
+
+	req := <-seen
+	wantHost := fmt.Sprintf("localhost:%d", port)
+	if req.Host != wantHost || req.Header.Get("Origin") != "http://"+wantHost || req.Header.Get("X-Env") != "dev" {
+		t.Errorf("backend saw Host %q, Origin %q, X-Env %q", req.Host, req.Header.Get("Origin"), req.Header.Get("X-Env"))
+	}
+}
@@ -0,0 +1,361 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type CapturedBody struct {
+	Size      int64  `json:"size"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Binary    bool   `json:"binary,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Base64    string `json:"base64,omitempty"`
+}
+
+type Timings struct {
+	Connect   float64 `json:"connect_ms"`
+	FirstByte float64 `json:"first_byte_ms"`
+	Total     float64 `json:"total_ms"`
+}
+
+type Exchange struct {
+	ID              string       `json:"id"`
+	Time            time.Time    `json:"time"`
+	Route           string       `json:"route"`
+	Method          string       `json:"method"`
+	URL             string       `json:"url"`
+	Proto           string       `json:"proto"`
+	ClientIP        string       `json:"client_ip"`
+	RequestHeaders  http.Header  `json:"request_headers"`
+	RequestBody     CapturedBody `json:"request_body"`
+	Status          int          `json:"status"`
+	ResponseHeaders http.Header  `json:"response_headers"`
+	ResponseBody    CapturedBody `json:"response_body"`
+	Timings         Timings      `json:"timings"`
+	Error           string       `json:"error,omitempty"`
+	Imported        bool         `json:"imported,omitempty"`
+}
+
+type ExchangeFilter struct {
+	Route  string
+	Status string
+	Method string
+	Text   string
+	Since  time.Time
+	Until  time.Time
+}
+
+func filterFromQuery(q url.Values) (ExchangeFilter, error) {
+	f := ExchangeFilter{
+		Route:  strings.TrimSpace(q.Get("route")),
+		Status: strings.TrimSpace(q.Get("status")),
+		Method: strings.TrimSpace(q.Get("method")),
+		Text:   strings.TrimSpace(q.Get("q")),
+	}
+	var err error
+	now := time.Now()
+	if f.Since, err = parseTimeBound(q.Get("since"), now); err != nil {
+		return f, err
+	}
+	if f.Until, err = parseTimeBound(q.Get("until"), now); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+func parseTimeBound(v string, now time.Time) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339 or a duration such as 15m", v)
+	}
+	return t, nil
+}
+
+func (f ExchangeFilter) Matches(e *Exchange) bool {
+	if f.Route != "" && !strings.EqualFold(f.Route, e.Route) {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	if f.Method != "" && !strings.EqualFold(f.Method, e.Method) {
+		return false
+	}
+	if f.Status != "" {
+		status := strconv.Itoa(e.Status)
+		if class, ok := strings.CutSuffix(strings.ToLower(f.Status), "xx"); ok {
+			if !strings.HasPrefix(status, class) {
+				return false
+			}
+		} else if status != f.Status {
+			return false
+		}
+	}
+	if f.Text != "" && !e.contains(strings.ToLower(f.Text)) {
+		return false
+	}
+	return true
+}
+
+func (e *Exchange) contains(text string) bool {
+	fields := []string{e.URL, e.RequestBody.Text, e.ResponseBody.Text, e.Error}
+	for _, h := range []http.Header{e.RequestHeaders, e.ResponseHeaders} {
+		for name, values := range h {
+			fields = append(fields, name+": "+strings.Join(values, ", "))
+		}
+	}
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), text) {
+			return true
+		}
+	}
+	return false
+}
+
+type inspector struct {
+	mu        sync.Mutex
+	exchanges []*Exchange
+	size      int
+	bodyLimit int
+}
+
+func newInspector(size, bodyLimit int) *inspector {
+	if size <= 0 {
+		return nil
+	}
+	return &inspector{size: size, bodyLimit: bodyLimit}
+}
+
+func (in *inspector) add(e *Exchange) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.exchanges = append(in.exchanges, e)
+	if len(in.exchanges) > in.size {
+		in.exchanges = in.exchanges[len(in.exchanges)-in.size:]
+	}
+}
+
+func (in *inspector) list(f ExchangeFilter) []*Exchange {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	var out []*Exchange
+	for i := len(in.exchanges) - 1; i >= 0; i-- {
+		if f.Matches(in.exchanges[i]) {
+			out = append(out, in.exchanges[i])
+		}
+	}
+	return out
+}
+
+func (in *inspector) get(id string) *Exchange {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for _, e := range in.exchanges {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+type bodyCapture struct {
+	mu    sync.Mutex
+	buf   []byte
+	size  int64
+	limit int
+}
+
+func (c *bodyCapture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size += int64(len(p))
+	if room := c.limit - len(c.buf); room > 0 {
+		c.buf = append(c.buf, p[:min(room, len(p))]...)
+	}
+	return len(p), nil
+}
+
+func (c *bodyCapture) body(h http.Header) CapturedBody {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return capturedBody(c.buf, c.size, c.limit, h)
+}
+
+type teeBody struct {
+	io.ReadCloser
+	capture *bodyCapture
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.capture.Write(p[:n])
+	return n, err
+}
+
+type pendingExchange struct {
+	exchange *Exchange
+	start    time.Time
+	req      *bodyCapture
+	resp     *bodyCapture
+
+	mu           sync.Mutex
+	connectStart time.Time
+	connect      time.Duration
+	firstByte    time.Duration
+}
+
+func exchangeRoute(rt *route) string {
+	if rt.name == "" {
+		return strconv.Itoa(rt.port)
+	}
+	return rt.label()
+}
+
+func (in *inspector) start(w *responseWriter, r *http.Request, rt *route, requestID string) (*pendingExchange, *http.Request) {
+	scheme := "https"
+	if isPlainHTTP(r) {
+		scheme = "http"
+	}
+
+	p := &pendingExchange{
+		start: time.Now(),
+		req:   &bodyCapture{limit: in.bodyLimit},
+		resp:  &bodyCapture{limit: in.bodyLimit},
+		exchange: &Exchange{
+			ID:             requestID,
+			Route:          exchangeRoute(rt),
+			Method:         r.Method,
+			URL:            scheme + "://" + r.Host + r.URL.RequestURI(),
+			Proto:          r.Proto,
+			RequestHeaders: r.Header.Clone(),
+		},
+	}
+	p.exchange.Time = p.start
+	if ip := remoteIP(r.RemoteAddr); ip != nil {
+		p.exchange.ClientIP = ip.String()
+	}
+
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = &teeBody{ReadCloser: r.Body, capture: p.req}
+	}
+	w.capture = p.resp
+
+	trace := &httptrace.ClientTrace{
+		ConnectStart: func(_, _ string) {
+			p.mu.Lock()
+			p.connectStart = time.Now()
+			p.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, _ error) {
+			p.mu.Lock()
+			p.connect = time.Since(p.connectStart)
+			p.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			p.mu.Lock()
+			p.firstByte = time.Since(p.start)
+			p.mu.Unlock()
+		},
+	}
+	return p, r.WithContext(httptrace.WithClientTrace(r.Context(), trace))
+}
+
+func (in *inspector) finish(p *pendingExchange, w *responseWriter) {
+	e := p.exchange
+	e.Status = w.statusCode
+	e.ResponseHeaders = w.Header().Clone()
+	e.RequestBody = p.req.body(e.RequestHeaders)
+	e.ResponseBody = p.resp.body(e.ResponseHeaders)
+	if w.err != nil {
+		e.Error = w.err.Error()
+	}
+
+	p.mu.Lock()
+	e.Timings = Timings{
+		Connect:   milliseconds(p.connect),
+		FirstByte: milliseconds(p.firstByte),
+		Total:     milliseconds(time.Since(p.start)),
+	}
+	p.mu.Unlock()
+	in.add(e)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func capturedBody(data []byte, size int64, limit int, h http.Header) CapturedBody {
+	b := CapturedBody{Size: size, Truncated: size > int64(len(data))}
+	if size == 0 {
+		return b
+	}
+
+	if strings.EqualFold(h.Get("Content-Encoding"), "gzip") && !b.Truncated {
+		if gz, err := gzip.NewReader(bytes.NewReader(data)); err == nil {
+			if plain, err := io.ReadAll(io.LimitReader(gz, int64(limit)+1)); err == nil {
+				b.Truncated = len(plain) > limit
+				data = plain[:min(limit, len(plain))]
+			}
+		}
+	}
+
+	if isBinaryBody(h.Get("Content-Type"), data) {
+		b.Binary = true
+		b.Base64 = base64.StdEncoding.EncodeToString(data)
+		return b
+	}
+	b.Text = strings.ToValidUTF8(string(data), "\uFFFD")
+	return b
+}
+
+func isBinaryBody(contentType string, data []byte) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"),
+		strings.Contains(mediaType, "json"),
+		strings.Contains(mediaType, "xml"),
+		strings.Contains(mediaType, "javascript"),
+		mediaType == "application/x-www-form-urlencoded",
+		mediaType == "application/graphql":
+		return false
+	case mediaType != "":
+		return true
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return true
+	}
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		if utf8.RuneStart(data[len(data)-i]) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				data = data[:len(data)-i]
+			}
+			break
+		}
+	}
+	return !utf8.Valid(data)
+}
@@ -0,0 +1,262 @@
+package proxy
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/imcanugur/httpsify/internal/version"
+)
+
+//go:embed inspector.html
+var inspectorPageHTML string
+
+func (s *Server) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) serveExchangesAPI(w http.ResponseWriter, r *http.Request) {
+	if s.inspector == nil {
+		s.writeJSONError(w, http.StatusNotFound, "Traffic inspector is disabled", "Start httpsify with --inspect set above 0", "--inspect 100")
+		return
+	}
+	if id, ok := strings.CutPrefix(r.URL.Path, "/api/exchanges/"); ok {
+		if id, ok := strings.CutSuffix(id, "/replay"); ok {
+			s.serveReplayAPI(w, r, id)
+			return
+		}
+		e := s.inspector.get(id)
+		if e == nil {
+			s.writeJSONError(w, http.StatusNotFound, fmt.Sprintf("Exchange %s not found", id), "Only recent exchanges are kept", "")
+			return
+		}
+		s.writeJSON(w, e)
+		return
+	}
+	filter, err := filterFromQuery(r.URL.Query())
+	if err != nil {
+		s.writeJSONError(w, http.StatusBadRequest, err.Error(), "", "/api/exchanges?since=15m")
+		return
+	}
+	exchanges := s.inspector.list(filter)
+	if exchanges == nil {
+		exchanges = []*Exchange{}
+	}
+	s.writeJSON(w, exchanges)
+}
+
+func exchangeStatus(e *Exchange) (string, string) {
+	class := "port-action"
+	if e.Status >= 400 || e.Error != "" {
+		class = "port-action rejected"
+	}
+	return fmt.Sprintf("%d &middot; %.0fms", e.Status, e.Timings.Total), class
+}
+
+func exchangePath(e *Exchange) string {
+	if u, err := url.Parse(e.URL); err == nil {
+		return u.Host + u.RequestURI()
+	}
+	return e.URL
+}
+
+func (s *Server) serveInspectorPage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter, filterErr := filterFromQuery(q)
+	filterQuery := url.Values{}
+	for _, key := range []string{"route", "status", "method", "since", "q"} {
+		if v := q.Get(key); v != "" {
+			filterQuery.Set(key, v)
+		}
+	}
+
+	var exchanges []*Exchange
+	capacity := 0
+	if s.inspector != nil {
+		exchanges = s.inspector.list(filter)
+		capacity = s.inspector.size
+	}
+
+	selected := q.Get("id")
+	var listHTML strings.Builder
+	if s.inspector == nil {
+		listHTML.WriteString(`<div class="empty">The inspector is disabled. Start httpsify with --inspect 100 to capture traffic.</div>`)
+	} else if filterErr != nil {
+		listHTML.WriteString(`<div class="empty">` + html.EscapeString(filterErr.Error()) + `</div>`)
+	} else if len(exchanges) == 0 {
+		listHTML.WriteString(`<div class="empty">No exchanges captured yet.</div>`)
+	}
+	for _, e := range exchanges {
+		link := url.Values{}
+		for k, v := range filterQuery {
+			link[k] = v
+		}
+		link.Set("id", e.ID)
+		class := "port-item"
+		if e.ID == selected {
+			class += " selected"
+		}
+		status, statusClass := exchangeStatus(e)
+		listHTML.WriteString(fmt.Sprintf(`
+            <a href="/inspector?%s" class="%s" title="%s">
+                <span class="port-name">%s %s</span>
+                <span class="%s">%s</span>
+            </a>`, html.EscapeString(link.Encode()), class, html.EscapeString(e.ID),
+			html.EscapeString(e.Method), html.EscapeString(exchangePath(e)), statusClass, status))
+	}
+
+	detailHTML, detailClass := "", "hidden"
+	if selected != "" && s.inspector != nil {
+		if e := s.inspector.get(selected); e != nil {
+			detailHTML, detailClass = renderExchange(e)+renderReplayForm(e, s.csrfToken(r)), ""
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "no-store")
+
+	output := strings.NewReplacer(
+		"{{.CSRF_TOKEN}}", s.csrfToken(r),
+		"{{.CAPACITY}}", strconv.Itoa(capacity),
+		"{{.FILTER_ROUTE}}", html.EscapeString(filter.Route),
+		"{{.FILTER_STATUS}}", html.EscapeString(filter.Status),
+		"{{.FILTER_METHOD}}", html.EscapeString(filter.Method),
+		"{{.FILTER_SINCE}}", html.EscapeString(q.Get("since")),
+		"{{.FILTER_Q}}", html.EscapeString(filter.Text),
+		"{{.FILTER_QUERY}}", html.EscapeString(filterQuery.Encode()),
+		"{{.COUNT}}", strconv.Itoa(len(exchanges)),
+		"{{.EXCHANGE_LIST}}", listHTML.String(),
+		"{{.DETAIL_CLASS}}", detailClass,
+		"{{.DETAIL}}", detailHTML,
+		"{{.VERSION}}", version.Get().Version,
+	).Replace(inspectorPageHTML)
+	w.Write([]byte(output))
+}
+
+func renderExchange(e *Exchange) string {
+	var b strings.Builder
+	status, statusClass := exchangeStatus(e)
+	b.WriteString(fmt.Sprintf(`
+            <div class="section-header">
+                <span class="section-title">%s</span>
+                <span class="%s">%s</span>
+            </div>`, html.EscapeString(e.ID), statusClass, status))
+
+	overview := [][2]string{
+		{"URL", e.URL},
+		{"Route", e.Route},
+		{"Time", e.Time.Format("2006-01-02 15:04:05.000")},
+		{"Client", e.ClientIP},
+		{"Protocol", e.Proto},
+		{"Timings", fmt.Sprintf("connect %.1fms, first byte %.1fms, total %.1fms", e.Timings.Connect, e.Timings.FirstByte, e.Timings.Total)},
+	}
+	if e.Error != "" {
+		overview = append(overview, [2]string{"Error", e.Error})
+	}
+	if e.Imported {
+		overview = append(overview, [2]string{"Source", "Imported from a HAR file"})
+	}
+	b.WriteString(renderPairs("Overview", overview))
+	b.WriteString(renderPairs("Request Headers", headerPairs(e.RequestHeaders)))
+	b.WriteString(renderBody("Request Body", e.RequestBody))
+	b.WriteString(renderPairs("Response Headers", headerPairs(e.ResponseHeaders)))
+	b.WriteString(renderBody("Response Body", e.ResponseBody))
+	return b.String()
+}
+
+func headerPairs(h http.Header) [][2]string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pairs [][2]string
+	for _, name := range names {
+		for _, v := range h[name] {
+			pairs = append(pairs, [2]string{name, v})
+		}
+	}
+	return pairs
+}
+
+func renderPairs(label string, pairs [][2]string) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf(`
+            <div class="detail-row">
+                <div class="detail-label">%s</div>
+                <div class="header-list">`, label))
+	for _, p := range pairs {
+		b.WriteString(fmt.Sprintf(`
+                    <div class="header-item"><span class="header-key">%s</span><span class="header-val">%s</span></div>`,
+			html.EscapeString(p[0]), html.EscapeString(p[1])))
+	}
+	if len(pairs) == 0 {
+		b.WriteString(`<div class="empty">None</div>`)
+	}
+	b.WriteString(`
+                </div>
+            </div>`)
+	return b.String()
+}
+
+func renderBody(label string, body CapturedBody) string {
+	content := html.EscapeString(body.Text)
+	switch {
+	case body.Size == 0:
+		content = "(empty)"
+	case body.Binary:
+		content = fmt.Sprintf("(binary, %d bytes)", body.Size)
+	case body.Truncated:
+		content += fmt.Sprintf("\n\n(truncated, %d bytes total)", body.Size)
+	}
+	return fmt.Sprintf(`
+            <div class="detail-row">
+                <div class="detail-label">%s</div>
+                <div class="body-snippet">%s</div>
+            </div>`, label, content)
+}
+
+func renderReplayForm(e *Exchange, csrf string) string {
+	path := e.URL
+	if u, err := url.Parse(e.URL); err == nil {
+		path = u.RequestURI()
+	}
+	var headers strings.Builder
+	for _, p := range headerPairs(e.RequestHeaders) {
+		headers.WriteString(p[0] + ": " + p[1] + "\n")
+	}
+
+	body := `<div class="empty">Binary body is replayed unchanged.</div>`
+	if !e.RequestBody.Binary {
+		note := ""
+		if e.RequestBody.Truncated {
+			note = `<div class="empty">The captured body was truncated; complete it before replaying.</div>`
+		}
+		body = note + `<textarea name="body" rows="6">` + html.EscapeString(e.RequestBody.Text) + `</textarea>`
+	}
+
+	return fmt.Sprintf(`
+            <form class="replay" method="post" action="/inspector/replay">
+                <div class="detail-label">Edit and Replay</div>
+                <input type="hidden" name="csrf_token" value="%s">
+                <input type="hidden" name="id" value="%s">
+                <div class="filters">
+                    <input name="route" placeholder="route" value="%s">
+                    <input name="method" placeholder="method" value="%s">
+                    <input name="path" placeholder="path" value="%s" style="grid-column: span 3;">
+                    <button type="submit">Replay</button>
+                </div>
+                <textarea name="headers" rows="8">%s</textarea>
+                %s
+            </form>`, html.EscapeString(csrf), html.EscapeString(e.ID), html.EscapeString(e.Route),
+		html.EscapeString(e.Method), html.EscapeString(path), html.EscapeString(headers.String()), body)
+}
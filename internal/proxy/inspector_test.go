@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imcanugur/httpsify/internal/config"
+	"github.com/imcanugur/httpsify/internal/logging"
+)
+
+func TestTrafficInspector(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n\x1a\n\x00\x00"))
+		case "/gzip":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte("compressed hello"))
+			gz.Close()
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"echo":%q}`, body)
+		}
+	}))
+	defer backend.Close()
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.InspectSize = 3
+	cfg.InspectBodyLimit = 16
+	cfg.Routes = []config.Route{{Name: "shop", Port: port}}
+	s := newTestServer(t, cfg)
+
+	send := func(method, path, body string) string {
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, newLocalRequest(method, "https://shop.localhost"+path, strings.NewReader(body)))
+		return rr.Header().Get("X-Request-ID")
+	}
+	send("GET", "/evicted", "")
+	postID := send("POST", "/echo?x=1", "hello inspector, this is long")
+	send("GET", "/image", "")
+	send("GET", "/missing", "")
+
+	e := s.inspector.get(postID)
+	if e == nil {
+		t.Fatalf("exchange %s not captured", postID)
+	}
+	if e.Route != "shop" || e.Method != "POST" || e.URL != "https://shop.localhost/echo?x=1" || e.Status != http.StatusOK {
+		t.Errorf("exchange = %s %s %s %d", e.Route, e.Method, e.URL, e.Status)
+	}
+	if e.RequestBody.Text != "hello inspector," || !e.RequestBody.Truncated || e.RequestBody.Size != 29 {
+		t.Errorf("request body = %+v, want 16 captured of 29 bytes", e.RequestBody)
+	}
+	if e.ResponseHeaders.Get("Content-Type") != "application/json" || e.Timings.Total <= 0 {
+		t.Errorf("response headers/timings = %v %+v", e.ResponseHeaders, e.Timings)
+	}
+	if got := s.inspector.list(ExchangeFilter{}); len(got) != 3 || got[2].ID != postID {
+		t.Fatalf("ring holds %d exchanges, want 3 with the POST oldest", len(got))
+	}
+
+	filters := []struct {
+		filter ExchangeFilter
+		want   int
+	}{
+		{ExchangeFilter{Method: "post"}, 1},
+		{ExchangeFilter{Status: "2xx"}, 2},
+		{ExchangeFilter{Status: "404"}, 1},
+		{ExchangeFilter{Route: "other"}, 0},
+		{ExchangeFilter{Text: "HELLO INSPECTOR"}, 1},
+		{ExchangeFilter{Text: "image/png"}, 1},
+	}
+	for _, tt := range filters {
+		if got := s.inspector.list(tt.filter); len(got) != tt.want {
+			t.Errorf("list(%+v) = %d exchanges, want %d", tt.filter, len(got), tt.want)
+		}
+	}
+
+	image := s.inspector.list(ExchangeFilter{Text: "image/png"})[0]
+	if !image.ResponseBody.Binary || image.ResponseBody.Base64 == "" || image.ResponseBody.Text != "" {
+		t.Errorf("image body = %+v, want base64 binary capture", image.ResponseBody)
+	}
+
+	cfg.InspectBodyLimit = 64
+	s = NewServer(cfg, logging.NewLogger(false, false))
+	gzipID := send("GET", "/gzip", "")
+	if body := s.inspector.get(gzipID).ResponseBody; body.Text != "compressed hello" || body.Binary {
+		t.Errorf("gzip body = %+v, want decoded text", body)
+	}
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://localhost/api/exchanges/"+gzipID, nil))
+	var got Exchange
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil || got.ID != gzipID {
+		t.Errorf("API exchange = %+v, %v", got, err)
+	}
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://localhost/inspector?id="+gzipID, nil))
+	if !strings.Contains(rr.Body.String(), "compressed hello") {
+		t.Error("inspector page does not show the selected exchange")
+	}
+}
@@ -6,10 +6,14 @@ import (
 	"fmt"
 	"html"
 	"net/http"
+	"net/url"
+	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/imcanugur/httpsify/internal/config"
 	"github.com/imcanugur/httpsify/internal/netutil"
 	"github.com/imcanugur/httpsify/internal/version"
 )
@@ -17,7 +21,14 @@ import (
 //go:embed landing.html
 var landingPageHTML string
 
-func (s *Server) serveLandingPage(w http.ResponseWriter, r *http.Request) {
+func routeTarget(rt config.Route) string {
+	if rt.IsLocal() && rt.Socket == "" && !rt.IsStatic() {
+		return strconv.Itoa(rt.Port)
+	}
+	return rt.Address()
+}
+
+func (s *Server) serveLandingPage(w http.ResponseWriter, r *http.Request, session bool) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
@@ -38,18 +49,71 @@ func (s *Server) serveLandingPage(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	aliases := make(map[int][]string)
+	for _, rt := range s.cfg.Routes {
+		if rt.IsLocal() && rt.Socket == "" && !rt.IsStatic() {
+			aliases[rt.Port] = append(aliases[rt.Port], rt.String())
+		}
+	}
+
+	sockets := s.GetListeningSockets()
+	sort.Slice(sockets, func(i, j int) bool {
+		return sockets[i].Socket < sockets[j].Socket
+	})
+
+	active := make(map[int]bool)
+	for _, svc := range services {
+		active[svc.Port] = true
+	}
+	activeSockets := make(map[string]bool)
+	for _, svc := range sockets {
+		activeSockets[svc.Socket] = true
+	}
+
+	var routeHTML strings.Builder
+	for _, rt := range s.cfg.Routes {
+		status := "Offline"
+		if !rt.IsLocal() {
+			status = "Remote"
+		} else if rt.IsStatic() {
+			if info, err := os.Stat(rt.Dir); err == nil && info.IsDir() {
+				status = "Static"
+			}
+		} else if active[rt.Port] || activeSockets[rt.Socket] {
+			status = "Proxy Ready"
+		}
+		name := rt.Name
+		if rt.Name == s.cfg.DefaultRoute {
+			name += " (default)"
+		}
+		routeHTML.WriteString(fmt.Sprintf(`
+            <a href="%s" class="port-item">
+                <span class="port-name">%s &rarr; %s</span>
+                <span class="port-action">%s</span>
+            </a>`, html.EscapeString(s.routeURL(rt)), html.EscapeString(s.cfg.RouteHost(name)+rt.Path), html.EscapeString(routeTarget(rt)), status))
+	}
+
+	routeSectionClass := ""
+	if len(s.cfg.Routes) == 0 {
+		routeSectionClass = "hidden"
+	}
+
 	var httpHTML strings.Builder
 	if len(webServices) == 0 {
 		httpHTML.WriteString("<div style=\"font-size: 13px; color: var(--muted); font-style: italic;\">No proxy-ready services detected.</div>")
 	} else {
 		for _, svc := range webServices {
-			url := fmt.Sprintf("https://%d.localhost", svc.Port)
+			url := s.portURL(svc.Port, svc.ProcessName)
 			svcJSON, _ := json.Marshal(svc)
+			name := html.EscapeString(s.cfg.PortHost(svc.Port, svc.ProcessName))
+			if names := aliases[svc.Port]; len(names) > 0 {
+				name += " &middot; " + html.EscapeString(strings.Join(names, ", "))
+			}
 			httpHTML.WriteString(fmt.Sprintf(`
             <a href="%s" class="port-item" data-service='%s'>
-                <span class="port-name">%d.localhost</span>
+                <span class="port-name">%s</span>
                 <span class="port-action">Proxy Ready</span>
-            </a>`, url, html.EscapeString(string(svcJSON)), svc.Port))
+            </a>`, html.EscapeString(url), html.EscapeString(string(svcJSON)), name))
 		}
 	}
 
@@ -63,6 +127,161 @@ func (s *Server) serveLandingPage(w http.ResponseWriter, r *http.Request) {
         </div>`, html.EscapeString(string(svcJSON)), svc.Port))
 	}
 
+	socketRoutes := make(map[string]config.Route)
+	for _, rt := range s.cfg.Routes {
+		if rt.Socket != "" {
+			if _, ok := socketRoutes[rt.Socket]; !ok {
+				socketRoutes[rt.Socket] = rt
+			}
+		}
+	}
+
+	var socketHTML strings.Builder
+	for _, svc := range sockets {
+		svcJSON, _ := json.Marshal(svc)
+		if rt, ok := socketRoutes[svc.Socket]; ok {
+			socketHTML.WriteString(fmt.Sprintf(`
+            <a href="%s" class="port-item" data-service='%s'>
+                <span class="port-name">%s</span>
+                <span class="port-action">Proxy Ready</span>
+            </a>`, html.EscapeString(s.routeURL(rt)), html.EscapeString(string(svcJSON)), html.EscapeString(s.cfg.RouteHost(rt.Name)+rt.Path)))
+			continue
+		}
+
+		action := "Add Route"
+		if !svc.IsWeb {
+			action = "Socket"
+		}
+		socketHTML.WriteString(fmt.Sprintf(`
+        <div class="port-item other-service" data-service='%s' title="--routes &lt;name&gt;=unix:%s">
+            <span class="port-name">%s</span>
+            <span class="port-action">%s</span>
+        </div>`, html.EscapeString(string(svcJSON)), html.EscapeString(svc.Socket), html.EscapeString(svc.Socket), action))
+	}
+
+	socketSectionClass := ""
+	if len(sockets) == 0 {
+		socketSectionClass = "hidden"
+	}
+
+	corsEvents := s.cors.recent()
+	var corsHTML strings.Builder
+	for _, e := range corsEvents {
+		kind := e.Method
+		if e.Preflight {
+			kind = "Preflight"
+		}
+		action, class := "Allowed", "port-action"
+		if !e.Allowed {
+			action, class = "Rejected", "port-action rejected"
+		}
+		corsHTML.WriteString(fmt.Sprintf(`
+        <div class="port-item" title="%s">
+            <span class="port-name">%s &middot; %s &rarr; %s</span>
+            <span class="%s">%s</span>
+        </div>`, html.EscapeString(e.Reason), html.EscapeString(kind), html.EscapeString(e.Origin), html.EscapeString(e.Host), class, action))
+	}
+
+	corsSectionClass := ""
+	if len(corsEvents) == 0 {
+		corsSectionClass = "hidden"
+	}
+
+	var trafficHTML strings.Builder
+	trafficSectionClass := "hidden"
+	if s.inspector != nil {
+		trafficSectionClass = ""
+		recent := s.inspector.list(ExchangeFilter{})
+		if len(recent) == 0 {
+			trafficHTML.WriteString("<div style=\"font-size: 13px; color: var(--muted); font-style: italic;\">No traffic captured yet.</div>")
+		}
+		for i, e := range recent {
+			if i >= 5 {
+				break
+			}
+			status, class := exchangeStatus(e)
+			trafficHTML.WriteString(fmt.Sprintf(`
+        <a href="/inspector?id=%s" class="port-item">
+            <span class="port-name">%s %s</span>
+            <span class="%s">%s</span>
+        </a>`, html.EscapeString(e.ID), html.EscapeString(e.Method), html.EscapeString(exchangePath(e)), class, status))
+		}
+	}
+
+	faults := s.Faults()
+	var faultHTML strings.Builder
+	for _, f := range faults {
+		action, class, next := "On", "toggle-btn", "false"
+		if f.Disabled {
+			action, class, next = "Off", "toggle-btn off", "true"
+		}
+		faultHTML.WriteString(fmt.Sprintf(`
+        <div class="port-item" title="%d injected">
+            <span class="port-name">%s &middot; %s</span>
+            <form method="post" action="/faults" style="margin: 0;">
+                <input type="hidden" name="csrf_token" value="%s">
+                <input type="hidden" name="name" value="%s">
+                <input type="hidden" name="enabled" value="%s">
+                <button type="submit" class="%s">%s</button>
+            </form>
+        </div>`, f.Injected, html.EscapeString(f.Name), html.EscapeString(f.Summary()),
+			html.EscapeString(s.csrfToken(r)), html.EscapeString(f.Name), next, class, action))
+	}
+
+	faultSectionClass := ""
+	if len(faults) == 0 {
+		faultSectionClass = "hidden"
+	}
+
+	held := s.HeldRequests()
+	heldKeys := make([]string, 0, len(held))
+	for key := range held {
+		heldKeys = append(heldKeys, key)
+	}
+	sort.Strings(heldKeys)
+	var heldHTML strings.Builder
+	for _, key := range heldKeys {
+		heldHTML.WriteString(fmt.Sprintf(`
+        <div class="port-item">
+            <span class="port-name">%s</span>
+            <span class="port-action">%d held</span>
+        </div>`, html.EscapeString(key), held[key]))
+	}
+
+	heldSectionClass := ""
+	if len(held) == 0 {
+		heldSectionClass = "hidden"
+	}
+
+	var networkHTML strings.Builder
+	for _, n := range s.network {
+		networkHTML.WriteString(fmt.Sprintf(`
+        <div class="port-item">
+            <span class="port-name">%s &rarr; %s</span>
+            <span class="port-action">%s</span>
+        </div>`, html.EscapeString(n.rule.Target()), html.EscapeString(n.profile.Name), html.EscapeString(n.profile.Summary())))
+	}
+
+	networkSectionClass := ""
+	if len(s.network) == 0 {
+		networkSectionClass = "hidden"
+	}
+
+	mirrors := s.Mirrors()
+	var mirrorHTML strings.Builder
+	for _, m := range mirrors {
+		mirrorHTML.WriteString(fmt.Sprintf(`
+        <a href="/mirrors?route=%s" class="port-item">
+            <span class="port-name">%s &rarr; :%d</span>
+            <span class="port-action">%d sent &middot; %d skipped &middot; %d diffs</span>
+        </a>`, url.QueryEscape(m.Route), html.EscapeString(m.Route), m.Port, m.Sent, m.Skipped, m.Differing+m.Failed))
+	}
+
+	mirrorSectionClass := ""
+	if len(mirrors) == 0 {
+		mirrorSectionClass = "hidden"
+	}
+
 	otherSectionClass := ""
 	if len(systemServices) == 0 {
 		otherSectionClass = "hidden"
@@ -80,9 +299,32 @@ func (s *Server) serveLandingPage(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	logoutClass := ""
+	if !session {
+		logoutClass = "hidden"
+	}
+
 	ver := version.Get()
 	output := strings.NewReplacer(
+		"{{.CSRF_TOKEN}}", s.csrfToken(r),
+		"{{.LOGOUT_CLASS}}", logoutClass,
+		"{{.ROUTE_SECTION_CLASS}}", routeSectionClass,
+		"{{.ROUTE_LIST}}", routeHTML.String(),
 		"{{.HTTP_LIST}}", httpHTML.String(),
+		"{{.SOCKET_SECTION_CLASS}}", socketSectionClass,
+		"{{.SOCKET_LIST}}", socketHTML.String(),
+		"{{.CORS_SECTION_CLASS}}", corsSectionClass,
+		"{{.CORS_LIST}}", corsHTML.String(),
+		"{{.TRAFFIC_SECTION_CLASS}}", trafficSectionClass,
+		"{{.TRAFFIC_LIST}}", trafficHTML.String(),
+		"{{.FAULT_SECTION_CLASS}}", faultSectionClass,
+		"{{.FAULT_LIST}}", faultHTML.String(),
+		"{{.HELD_SECTION_CLASS}}", heldSectionClass,
+		"{{.HELD_LIST}}", heldHTML.String(),
+		"{{.NETWORK_SECTION_CLASS}}", networkSectionClass,
+		"{{.NETWORK_LIST}}", networkHTML.String(),
+		"{{.MIRROR_SECTION_CLASS}}", mirrorSectionClass,
+		"{{.MIRROR_LIST}}", mirrorHTML.String(),
 		"{{.OTHER_SECTION_CLASS}}", otherSectionClass,
 		"{{.OTHER_LIST}}", otherHTML.String(),
 		"{{.VERSION}}", ver.Version,
@@ -0,0 +1,383 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+	"github.com/imcanugur/httpsify/internal/logging"
+)
+
+const (
+	maxMirrorBody    = 1 << 20
+	maxMirrorDiffs   = 200
+	maxMirrorChanges = 50
+	maxMirrorFlight  = 32
+	mirrorHeader     = "X-Httpsify-Mirror"
+)
+
+var (
+	errBodyUnread = errors.New("request body was not fully read")
+	errMirrorBusy = errors.New("too many shadow requests in flight")
+)
+
+var mirrorIgnoredHeaders = map[string]bool{
+	"Date":              true,
+	"Content-Length":    true,
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Transfer-Encoding": true,
+}
+
+type MirrorStatus struct {
+	config.MirrorRule
+	Sent        int64            `json:"sent"`
+	Failed      int64            `json:"failed"`
+	Differing   int64            `json:"differing"`
+	Skipped     int64            `json:"skipped"`
+	SkipReasons map[string]int64 `json:"skip_reasons,omitempty"`
+}
+
+type MirrorDiff struct {
+	ID            string         `json:"id"`
+	Time          time.Time      `json:"time"`
+	Route         string         `json:"route"`
+	ShadowPort    int            `json:"shadow_port"`
+	Method        string         `json:"method"`
+	URL           string         `json:"url"`
+	PrimaryStatus int            `json:"primary_status"`
+	ShadowStatus  int            `json:"shadow_status,omitempty"`
+	ShadowError   string         `json:"shadow_error,omitempty"`
+	Changes       []MirrorChange `json:"changes,omitempty"`
+}
+
+type MirrorChange struct {
+	Field   string `json:"field"`
+	Primary any    `json:"primary"`
+	Shadow  any    `json:"shadow"`
+}
+
+type mirrorSet struct {
+	mu     sync.Mutex
+	rules  []config.MirrorRule
+	status map[string]*MirrorStatus
+	diffs  []MirrorDiff
+	flight chan struct{}
+}
+
+func newMirrorSet(rules []config.MirrorRule) *mirrorSet {
+	m := &mirrorSet{rules: rules, status: make(map[string]*MirrorStatus), flight: make(chan struct{}, maxMirrorFlight)}
+	for _, rule := range rules {
+		m.status[rule.Route] = &MirrorStatus{MirrorRule: rule}
+	}
+	return m
+}
+
+func (m *mirrorSet) rule(route string) (config.MirrorRule, bool) {
+	for _, rule := range m.rules {
+		if strings.EqualFold(rule.Route, route) {
+			return rule, true
+		}
+	}
+	return config.MirrorRule{}, false
+}
+
+func (m *mirrorSet) record(rule config.MirrorRule, d *MirrorDiff) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.status[rule.Route]
+	st.Sent++
+	if d == nil {
+		return
+	}
+	if d.ShadowError != "" {
+		st.Failed++
+	} else {
+		st.Differing++
+	}
+	m.diffs = append(m.diffs, *d)
+	if len(m.diffs) > maxMirrorDiffs {
+		m.diffs = m.diffs[len(m.diffs)-maxMirrorDiffs:]
+	}
+}
+
+func (m *mirrorSet) skip(rule config.MirrorRule, reason error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.status[rule.Route]
+	st.Skipped++
+	if st.SkipReasons == nil {
+		st.SkipReasons = make(map[string]int64)
+	}
+	st.SkipReasons[reason.Error()]++
+}
+
+func (s *Server) skipMirror(r *http.Request, rule config.MirrorRule, reason error) {
+	s.mirrors.skip(rule, reason)
+	requestID, _ := r.Context().Value(logging.RequestIDKey).(string)
+	s.logger.Debug("mirror skipped", "request_id", requestID, "route", rule.Route, "reason", reason.Error())
+}
+
+func (s *Server) Mirrors() []MirrorStatus {
+	s.mirrors.mu.Lock()
+	defer s.mirrors.mu.Unlock()
+	out := make([]MirrorStatus, 0, len(s.mirrors.rules))
+	for _, rule := range s.mirrors.rules {
+		st := *s.mirrors.status[rule.Route]
+		st.SkipReasons = maps.Clone(st.SkipReasons)
+		out = append(out, st)
+	}
+	return out
+}
+
+func (s *Server) MirrorDiffs(route string) []MirrorDiff {
+	s.mirrors.mu.Lock()
+	defer s.mirrors.mu.Unlock()
+	out := make([]MirrorDiff, 0, len(s.mirrors.diffs))
+	for i := len(s.mirrors.diffs) - 1; i >= 0; i-- {
+		if route == "" || s.mirrors.diffs[i].Route == route {
+			out = append(out, s.mirrors.diffs[i])
+		}
+	}
+	return out
+}
+
+type mirrorResponse struct {
+	status int
+	header http.Header
+	body   CapturedBody
+	err    error
+}
+
+type mirrorKey struct{}
+
+type mirrorPrimary struct {
+	status int
+	header http.Header
+	body   bodyCapture
+}
+
+func (m *mirrorPrimary) capture(resp *http.Response) {
+	m.status, m.header = resp.StatusCode, resp.Header.Clone()
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(resp.Body, &m.body), resp.Body}
+}
+
+func (s *Server) startMirror(w *responseWriter, r *http.Request, rt *route) (*http.Request, func()) {
+	rule, ok := s.mirrors.rule(routeKey(rt))
+	if !ok {
+		return r, nil
+	}
+	if err := unreplayable(r, rt); err != nil {
+		s.skipMirror(r, rule, err)
+		return r, nil
+	}
+	r, _ = replayableBody(r, rt)
+	body, _ := r.Body.(*replayBody)
+	var primary *mirrorPrimary
+	if rule.Diff {
+		primary = &mirrorPrimary{body: bodyCapture{limit: maxMirrorBody}}
+		r = r.WithContext(context.WithValue(r.Context(), mirrorKey{}, primary))
+	}
+
+	return r, func() {
+		shadow := r.Clone(context.WithoutCancel(r.Context()))
+		if body != nil {
+			data, complete := body.recorded()
+			if !complete {
+				s.skipMirror(r, rule, errBodyUnread)
+				return
+			}
+			shadow.Body = io.NopCloser(bytes.NewReader(data))
+		}
+		var p mirrorResponse
+		if primary != nil {
+			p = mirrorResponse{status: primary.status, header: primary.header, body: primary.body.body(primary.header), err: w.err}
+		}
+		select {
+		case s.mirrors.flight <- struct{}{}:
+		default:
+			s.skipMirror(r, rule, errMirrorBusy)
+			return
+		}
+		go func() {
+			defer func() { <-s.mirrors.flight }()
+			s.mirrors.record(rule, diffMirror(shadow, rule, p, s.sendShadow(shadow, rt, rule)))
+		}()
+	}
+}
+
+func (s *Server) sendShadow(req *http.Request, rt *route, rule config.MirrorRule) mirrorResponse {
+	ctx, cancel := context.WithTimeout(req.Context(), time.Duration(s.cfg.WriteTimeout)*time.Second)
+	defer cancel()
+
+	req = req.Clone(ctx)
+	req.RequestURI = ""
+	rt.direct(req)
+	req.URL.Scheme = "http"
+	req.URL.Host = net.JoinHostPort("127.0.0.1", strconv.Itoa(rule.Port))
+	req.Header.Del("Connection")
+	req.Header.Set(mirrorHeader, "1")
+
+	resp, err := s.transport.RoundTrip(req)
+	if err != nil {
+		requestID, _ := req.Context().Value(logging.RequestIDKey).(string)
+		s.logger.Debug("mirror request failed", "request_id", requestID, "port", rule.Port, "error", err.Error())
+		return mirrorResponse{err: err}
+	}
+	defer resp.Body.Close()
+	capture := &bodyCapture{limit: maxMirrorBody}
+	_, err = io.Copy(capture, resp.Body)
+	return mirrorResponse{status: resp.StatusCode, header: resp.Header, body: capture.body(resp.Header), err: err}
+}
+
+func diffMirror(req *http.Request, rule config.MirrorRule, primary, shadow mirrorResponse) *MirrorDiff {
+	requestID, _ := req.Context().Value(logging.RequestIDKey).(string)
+	d := &MirrorDiff{
+		ID:            requestID,
+		Time:          time.Now(),
+		Route:         rule.Route,
+		ShadowPort:    rule.Port,
+		Method:        req.Method,
+		URL:           req.URL.RequestURI(),
+		PrimaryStatus: primary.status,
+		ShadowStatus:  shadow.status,
+	}
+	if shadow.err != nil {
+		d.ShadowError = shadow.err.Error()
+		return d
+	}
+	if !rule.Diff || primary.err != nil {
+		return nil
+	}
+
+	if primary.status != shadow.status {
+		d.Changes = append(d.Changes, MirrorChange{Field: "status", Primary: primary.status, Shadow: shadow.status})
+	}
+	d.Changes = append(d.Changes, diffHeaders(primary.header, shadow.header)...)
+	d.Changes = append(d.Changes, diffBodies(primary, shadow)...)
+	if len(d.Changes) == 0 {
+		return nil
+	}
+	if len(d.Changes) > maxMirrorChanges {
+		d.Changes = d.Changes[:maxMirrorChanges]
+	}
+	return d
+}
+
+func diffHeaders(primary, shadow http.Header) []MirrorChange {
+	names := make(map[string]bool)
+	for name := range primary {
+		names[name] = true
+	}
+	for name := range shadow {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		if !mirrorIgnoredHeaders[name] {
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+
+	var changes []MirrorChange
+	for _, name := range sorted {
+		p, sh := strings.Join(primary.Values(name), ", "), strings.Join(shadow.Values(name), ", ")
+		if p != sh {
+			changes = append(changes, MirrorChange{Field: "header " + name, Primary: p, Shadow: sh})
+		}
+	}
+	return changes
+}
+
+func diffBodies(primary, shadow mirrorResponse) []MirrorChange {
+	p, sh := primary.body, shadow.body
+	if p.Truncated || sh.Truncated || p.Binary || sh.Binary {
+		if p.Size != sh.Size || p.Base64 != sh.Base64 || p.Text != sh.Text {
+			return []MirrorChange{{Field: "body", Primary: fmt.Sprintf("%d bytes", p.Size), Shadow: fmt.Sprintf("%d bytes", sh.Size)}}
+		}
+		return nil
+	}
+	if isJSONType(primary.header) && isJSONType(shadow.header) {
+		var pv, sv any
+		if json.Unmarshal([]byte(p.Text), &pv) == nil && json.Unmarshal([]byte(sh.Text), &sv) == nil {
+			var changes []MirrorChange
+			diffJSON("body", pv, sv, &changes)
+			return changes
+		}
+	}
+	if p.Text != sh.Text {
+		return []MirrorChange{{Field: "body", Primary: p.Text, Shadow: sh.Text}}
+	}
+	return nil
+}
+
+func isJSONType(h http.Header) bool {
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func diffJSON(path string, p, s any, changes *[]MirrorChange) {
+	if len(*changes) >= maxMirrorChanges {
+		return
+	}
+	switch pv := p.(type) {
+	case map[string]any:
+		sv, ok := s.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make(map[string]bool)
+		for k := range pv {
+			keys[k] = true
+		}
+		for k := range sv {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			diffJSON(path+"."+k, pv[k], sv[k], changes)
+		}
+		return
+	case []any:
+		sv, ok := s.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < max(len(pv), len(sv)); i++ {
+			var a, b any
+			if i < len(pv) {
+				a = pv[i]
+			}
+			if i < len(sv) {
+				b = sv[i]
+			}
+			diffJSON(fmt.Sprintf("%s[%d]", path, i), a, b, changes)
+		}
+		return
+	}
+	if !reflect.DeepEqual(p, s) {
+		*changes = append(*changes, MirrorChange{Field: path, Primary: p, Shadow: s})
+	}
+}
@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/imcanugur/httpsify/internal/version"
+)
+
+//go:embed mirrors.html
+var mirrorsPageHTML string
+
+func (s *Server) serveMirrorsAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, http.StatusMethodNotAllowed, "Use GET to read mirrors", "", "")
+		return
+	}
+	if r.URL.Path == "/api/mirrors/diffs" {
+		s.writeJSON(w, s.MirrorDiffs(strings.ToLower(r.URL.Query().Get("route"))))
+		return
+	}
+	s.writeJSON(w, s.Mirrors())
+}
+
+func mirrorValue(v any) string {
+	if str, ok := v.(string); ok {
+		return str
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func skipReasons(reasons map[string]int64) string {
+	out := make([]string, 0, len(reasons))
+	for reason, n := range reasons {
+		out = append(out, fmt.Sprintf("%d skipped: %s", n, reason))
+	}
+	sort.Strings(out)
+	return strings.Join(out, "\n")
+}
+
+func renderMirrorDiff(d MirrorDiff) string {
+	var b strings.Builder
+	status, class := fmt.Sprintf("%d &rarr; %d", d.PrimaryStatus, d.ShadowStatus), "port-action rejected"
+	if d.ShadowError != "" {
+		status = "shadow failed"
+	}
+	b.WriteString(fmt.Sprintf(`
+        <div class="diff">
+            <div class="port-item" title="%s">
+                <span class="port-name">%s %s &bull; %s</span>
+                <span class="%s">%s</span>
+            </div>
+            <div class="detail-row">
+                <div class="header-list">`,
+		html.EscapeString(d.ID), html.EscapeString(d.Method), html.EscapeString(d.URL),
+		d.Time.Format("15:04:05"), class, status))
+	if d.ShadowError != "" {
+		b.WriteString(fmt.Sprintf(`
+                    <div class="header-item"><span class="header-key">error</span><span class="header-val">%s</span></div>`,
+			html.EscapeString(d.ShadowError)))
+	}
+	for _, c := range d.Changes {
+		b.WriteString(fmt.Sprintf(`
+                    <div class="header-item"><span class="header-key">%s</span><span class="header-val">%s<br><span class="shadow">%s</span></span></div>`,
+			html.EscapeString(c.Field), html.EscapeString(mirrorValue(c.Primary)), html.EscapeString(mirrorValue(c.Shadow))))
+	}
+	b.WriteString(`
+                </div>
+            </div>
+        </div>`)
+	return b.String()
+}
+
+func (s *Server) serveMirrorsPage(w http.ResponseWriter, r *http.Request) {
+	route := strings.ToLower(r.URL.Query().Get("route"))
+	filterQuery, filterLabel, clearClass := url.Values{}, "", "hidden"
+	if route != "" {
+		filterQuery.Set("route", route)
+		filterLabel, clearClass = " for "+html.EscapeString(route), ""
+	}
+
+	var rulesHTML strings.Builder
+	mirrors := s.Mirrors()
+	if len(mirrors) == 0 {
+		rulesHTML.WriteString(`<div class="empty">No mirrors configured. Start httpsify with --mirror api=8001 to copy traffic to a shadow port.</div>`)
+	}
+	for _, m := range mirrors {
+		class := "port-item"
+		if m.Route == route {
+			class += " selected"
+		}
+		diff := "off"
+		if m.Diff {
+			diff = strconv.FormatInt(m.Differing, 10)
+		}
+		rulesHTML.WriteString(fmt.Sprintf(`
+            <a href="/mirrors?route=%s" class="%s" title="%s">
+                <span class="port-name">%s &rarr; :%d</span>
+                <span class="port-action">%d sent &middot; %d skipped &middot; %d failed &middot; diffs %s</span>
+            </a>`, url.QueryEscape(m.Route), class, html.EscapeString(skipReasons(m.SkipReasons)), html.EscapeString(m.Route), m.Port, m.Sent, m.Skipped, m.Failed, diff))
+	}
+
+	diffs := s.MirrorDiffs(route)
+	var diffsHTML strings.Builder
+	if len(diffs) == 0 {
+		diffsHTML.WriteString(`<div class="empty">No differences recorded. Enable diffing with --mirror-diff.</div>`)
+	}
+	for _, d := range diffs {
+		diffsHTML.WriteString(renderMirrorDiff(d))
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "no-store")
+
+	output := strings.NewReplacer(
+		"{{.CAPACITY}}", strconv.Itoa(maxMirrorDiffs),
+		"{{.RULE_LIST}}", rulesHTML.String(),
+		"{{.COUNT}}", strconv.Itoa(len(diffs)),
+		"{{.FILTER_LABEL}}", filterLabel,
+		"{{.CLEAR_CLASS}}", clearClass,
+		"{{.FILTER_QUERY}}", html.EscapeString(filterQuery.Encode()),
+		"{{.DIFF_LIST}}", diffsHTML.String(),
+		"{{.VERSION}}", version.Get().Version,
+	).Replace(mirrorsPageHTML)
+	w.Write([]byte(output))
+}
@@ -0,0 +1,180 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestMirror(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/same" {
+			w.Write([]byte("ok"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Version", "1")
+		w.Write([]byte(`{"x": 1, "items": [{"id": "a"}]}`))
+	}))
+	defer primary.Close()
+
+	type seen struct {
+		method, body, mirror, env string
+	}
+	shadowSeen := make(chan seen, 10)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		shadowSeen <- seen{r.Method, string(body), r.Header.Get(mirrorHeader), r.Header.Get("X-Env")}
+		if r.URL.Path == "/same" {
+			w.Write([]byte("ok"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Version", "2")
+		w.Write([]byte(`{"x": 2, "items": [{"id": "a"}]}`))
+	}))
+	defer shadow.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadPort := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{
+		{Name: "api", Port: primary.Listener.Addr().(*net.TCPAddr).Port, Headers: &config.HeaderRules{
+			Request:  config.HeaderOps{Set: map[string]string{"X-Env": "dev"}},
+			Response: config.HeaderOps{Set: map[string]string{"X-Served-By": "httpsify"}},
+		}},
+		{Name: "dead", Port: primary.Listener.Addr().(*net.TCPAddr).Port},
+	}
+	cfg.Mirrors = []config.MirrorRule{
+		{Route: "api", Port: shadow.Listener.Addr().(*net.TCPAddr).Port, Diff: true},
+		{Route: "dead", Port: deadPort},
+	}
+	s := newTestServer(t, cfg)
+
+	waitMirror := func(route string, done func(MirrorStatus) bool) {
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			for _, m := range s.Mirrors() {
+				if m.Route == route && done(m) {
+					return
+				}
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("mirror %s = %+v", route, s.Mirrors())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://api.localhost/items", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("X-Version") != "1" || !strings.Contains(rr.Body.String(), `"x": 1`) {
+		t.Errorf("client got %d %q from the shadow", rr.Code, rr.Body.String())
+	}
+	if got := <-shadowSeen; got.method != "GET" || got.mirror != "1" || got.env != "dev" {
+		t.Errorf("shadow saw %+v", got)
+	}
+	waitMirror("api", func(m MirrorStatus) bool { return m.Differing == 1 })
+
+	diffs := s.MirrorDiffs("api")
+	if len(diffs) != 1 {
+		t.Fatalf("MirrorDiffs = %+v", diffs)
+	}
+	want := []MirrorChange{
+		{Field: "header X-Version", Primary: "1", Shadow: "2"},
+		{Field: "body.x", Primary: 1.0, Shadow: 2.0},
+	}
+	if d := diffs[0]; d.Method != "GET" || d.URL != "/items" || d.PrimaryStatus != 200 || !reflect.DeepEqual(d.Changes, want) {
+		t.Errorf("diff = %+v", d)
+	}
+
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("POST", "https://api.localhost/same", strings.NewReader("order=1")))
+	if rr.Code != http.StatusOK || rr.Body.String() != "ok" {
+		t.Errorf("POST = %d %q", rr.Code, rr.Body.String())
+	}
+	if got := <-shadowSeen; got.method != "POST" || got.body != "order=1" {
+		t.Errorf("shadow saw %+v", got)
+	}
+	waitMirror("api", func(m MirrorStatus) bool { return m.Sent == 2 })
+	if n := len(s.MirrorDiffs("api")); n != 1 {
+		t.Errorf("identical responses recorded a diff, have %d", n)
+	}
+
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://dead.localhost/items", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("dead shadow affected the client: %d", rr.Code)
+	}
+	waitMirror("dead", func(m MirrorStatus) bool { return m.Failed == 1 })
+	if d := s.MirrorDiffs("dead"); len(d) != 1 || d[0].ShadowError == "" {
+		t.Errorf("dead shadow diffs = %+v", d)
+	}
+
+	streaming := newLocalRequest("POST", "https://api.localhost/same", strings.NewReader("chunked"))
+	streaming.ContentLength = -1
+	for _, req := range []*http.Request{
+		streaming,
+		newLocalRequest("POST", "https://api.localhost/same", strings.NewReader(strings.Repeat("x", maxMirrorBody+1))),
+	} {
+		rr = httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("unmirrored POST = %d", rr.Code)
+		}
+	}
+	for range maxMirrorFlight {
+		s.mirrors.flight <- struct{}{}
+	}
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://api.localhost/same", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("GET with a full shadow pool = %d", rr.Code)
+	}
+	for range maxMirrorFlight {
+		<-s.mirrors.flight
+	}
+	for _, m := range s.Mirrors() {
+		want := map[string]int64{errStreamingBody.Error(): 1, errLargeBody.Error(): 1, errMirrorBusy.Error(): 1}
+		if m.Route == "api" && (m.Skipped != 3 || m.Sent != 2 || !reflect.DeepEqual(m.SkipReasons, want)) {
+			t.Errorf("skipped mirrors = %+v", m)
+		}
+	}
+
+	token, err := s.DashboardToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := newLocalRequest("GET", "https://localhost/api/mirrors/diffs?route=api", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	var exported []MirrorDiff
+	if err := json.Unmarshal(rr.Body.Bytes(), &exported); err != nil || len(exported) != 1 || exported[0].Route != "api" {
+		t.Errorf("exported diffs = %q (%v)", rr.Body.String(), err)
+	}
+
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://localhost/mirrors", nil))
+	if body := rr.Body.String(); !strings.Contains(body, "body.x") || !strings.Contains(body, "connection refused") || !strings.Contains(body, "1 skipped: "+errLargeBody.Error()) {
+		t.Error("mirrors page does not show the recorded diffs")
+	}
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://localhost/", nil))
+	if !strings.Contains(rr.Body.String(), "2 sent &middot; 3 skipped &middot; 1 diffs") {
+		t.Error("dashboard does not list mirrors")
+	}
+}
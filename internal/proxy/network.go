@@ -0,0 +1,165 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+type networkRule struct {
+	rule    config.NetworkRule
+	clients *config.ClientACL
+	profile config.NetworkProfile
+}
+
+func (s *Server) buildNetworkRules() []networkRule {
+	var rules []networkRule
+	for _, rule := range s.cfg.Network {
+		profile, ok := s.cfg.LookupNetworkProfile(rule.Profile)
+		if !ok {
+			continue
+		}
+		n := networkRule{rule: rule, profile: profile}
+		if len(rule.Clients) > 0 {
+			acl, err := config.ParseClientACL(rule.Clients)
+			if err != nil {
+				continue
+			}
+			n.clients = &acl
+		}
+		rules = append(rules, n)
+	}
+	return rules
+}
+
+func (s *Server) networkProfile(rt *route, ip net.IP) *config.NetworkProfile {
+	for i := range s.network {
+		n := &s.network[i]
+		if n.rule.Route != "" && !strings.EqualFold(n.rule.Route, routeKey(rt)) {
+			continue
+		}
+		if n.clients != nil && !n.clients.Contains(ip) {
+			continue
+		}
+		return &n.profile
+	}
+	return nil
+}
+
+func (s *Server) shapeNetwork(w *responseWriter, r *http.Request, rt *route) bool {
+	p := s.networkProfile(rt, remoteIP(r.RemoteAddr))
+	if p == nil {
+		return true
+	}
+	w.network = p
+	if err := sleep(r.Context(), time.Duration(p.RTTMS)*time.Millisecond); err != nil {
+		w.err = err
+		return false
+	}
+	w.downlink = &throttledWriter{w: w.ResponseWriter, flush: w.Flush, s: newShaper(r.Context(), p.DownKbps, p)}
+	if r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0 {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{&throttledReader{r: r.Body, s: newShaper(r.Context(), p.UpKbps, p)}, r.Body}
+	}
+	return true
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type shaper struct {
+	ctx       context.Context
+	rate      float64
+	stallRate float64
+	stall     time.Duration
+	next      time.Time
+}
+
+func newShaper(ctx context.Context, kbps int, p *config.NetworkProfile) *shaper {
+	return &shaper{
+		ctx:       ctx,
+		rate:      float64(kbps) * 1000 / 8,
+		stallRate: p.StallRate,
+		stall:     time.Duration(p.StallMS) * time.Millisecond,
+	}
+}
+
+func (s *shaper) chunkSize() int {
+	if s.rate == 0 {
+		return 16 << 10
+	}
+	// About 20ms of transmission per chunk keeps slow links from bursting.
+	return min(max(int(s.rate/50), 512), 16<<10)
+}
+
+func (s *shaper) pace(n int) error {
+	now := time.Now()
+	if s.next.Before(now) {
+		s.next = now
+	}
+	if s.rate > 0 {
+		s.next = s.next.Add(time.Duration(float64(n) / s.rate * float64(time.Second)))
+	}
+	if roll(s.stallRate) {
+		s.next = s.next.Add(s.stall)
+	}
+	return sleep(s.ctx, time.Until(s.next))
+}
+
+type throttledWriter struct {
+	w     io.Writer
+	flush func()
+	s     *shaper
+}
+
+func (t *throttledWriter) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		n, err := t.w.Write(b[:min(len(b), t.s.chunkSize())])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if t.flush != nil {
+			t.flush()
+		}
+		if err := t.s.pace(n); err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
+
+type throttledReader struct {
+	r io.Reader
+	s *shaper
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p[:min(len(p), t.s.chunkSize())])
+	if n > 0 {
+		if perr := t.s.pace(n); perr != nil && err == nil {
+			err = perr
+		}
+	}
+	return n, err
+}
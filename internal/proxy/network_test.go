@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestNetworkProfile(t *testing.T) {
+	payload := strings.Repeat("x", 20000)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Upload", strconv.Itoa(len(body)))
+		w.Write([]byte(payload))
+	}))
+	defer backend.Close()
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{{Name: "shop", Port: port}, {Name: "free", Port: port}}
+	cfg.NetworkProfiles = []config.NetworkProfile{{Name: "tiny", DownKbps: 800, UpKbps: 800, RTTMS: 50}}
+	cfg.Network = []config.NetworkRule{
+		{Route: "shop", Profile: "tiny"},
+		{Route: "free", Clients: []string{"10.0.0.0/8"}, Profile: "slow-3g"},
+	}
+	s := newTestServer(t, cfg)
+
+	tests := []struct {
+		name    string
+		host    string
+		body    string
+		minTime time.Duration
+		maxTime time.Duration
+	}{
+		// 20 KB at 100 KB/s plus one round trip.
+		{"download", "shop.localhost", "", 250 * time.Millisecond, 0},
+		{"upload", "shop.localhost", strings.Repeat("y", 10000), 350 * time.Millisecond, 0},
+		{"other client", "free.localhost", "", 0, 200 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newLocalRequest("POST", "https://"+tt.host+"/", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			start := time.Now()
+			s.ServeHTTP(rr, req)
+			elapsed := time.Since(start)
+			if rr.Code != http.StatusOK || rr.Body.String() != payload || rr.Header().Get("X-Upload") != strconv.Itoa(len(tt.body)) {
+				t.Fatalf("got %d with %d bytes", rr.Code, rr.Body.Len())
+			}
+			if elapsed < tt.minTime || (tt.maxTime > 0 && elapsed > tt.maxTime) {
+				t.Errorf("took %v, want between %v and %v", elapsed, tt.minTime, tt.maxTime)
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://localhost/", nil))
+	if !strings.Contains(rr.Body.String(), "Network Profiles") || !strings.Contains(rr.Body.String(), "shop &rarr; tiny") ||
+		!strings.Contains(rr.Body.String(), "free from 10.0.0.0/8 &rarr; slow-3g") {
+		t.Error("dashboard does not show the active network profiles")
+	}
+}
@@ -3,16 +3,16 @@ package proxy
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/http/httputil"
-	"net/url"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -24,15 +24,13 @@ import (
 	"github.com/imcanugur/httpsify/internal/netutil"
 )
 
-var (
-	hostPattern = regexp.MustCompile(`^(\d+)\.(localhost|localtest\.me)(?::\d+)?$`)
-	rootPattern = regexp.MustCompile(`^(localhost|localtest\.me)(?::\d+)?$`)
-)
+var errUnknownRoute = errors.New("unknown route")
 
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Hint    string `json:"hint,omitempty"`
-	Example string `json:"example,omitempty"`
+	Error       string   `json:"error"`
+	Hint        string   `json:"hint,omitempty"`
+	Example     string   `json:"example,omitempty"`
+	KnownRoutes []string `json:"known_routes,omitempty"`
 }
 
 type Server struct {
@@ -45,14 +43,39 @@ type Server struct {
 	requestCount  atomic.Uint64
 	localIPs      []string
 	ipsMutex      sync.RWMutex
+	routes        map[string][]*route
+	hosts         *hostMatcher
+	dialer        *net.Dialer
+	sockets       sync.Map
+	socketProbes  sync.Map
+	portSchemes   sync.Map
+	probeTLS      *http.Transport
+	probeH2C      *http.Transport
+	cors          corsLog
+	clientACLs    map[string]config.ClientACL
+	shareKey      secretFile
+	dashKey       secretFile
+	inspector     *inspector
+	faults        *faultSet
+	network       []networkRule
+	held          heldCounts
+	mirrors       *mirrorSet
 }
 
 func NewServer(cfg *config.Config, logger *logging.Logger) *Server {
-	tr := &http.Transport{
-		DialContext: (&net.Dialer{
+	s := &Server{
+		cfg:       cfg,
+		logger:    logger,
+		startTime: time.Now(),
+		localIPs:  netutil.GetLocalIPs(),
+		dialer: &net.Dialer{
 			Timeout:   time.Duration(cfg.DialTimeout) * time.Second,
 			KeepAlive: 30 * time.Second,
-		}).DialContext,
+		},
+	}
+
+	s.transport = &http.Transport{
+		DialContext:           s.dialContext(s.dialer),
 		MaxIdleConns:          100,
 		MaxIdleConnsPerHost:   20,
 		IdleConnTimeout:       time.Duration(cfg.IdleTimeout) * time.Second,
@@ -60,13 +83,22 @@ func NewServer(cfg *config.Config, logger *logging.Logger) *Server {
 		ExpectContinueTimeout: 1 * time.Second,
 		DisableCompression:    true,
 	}
-
-	s := &Server{
-		cfg:       cfg,
-		logger:    logger,
-		startTime: time.Now(),
-		transport: tr,
-		localIPs:  netutil.GetLocalIPs(),
+	s.hosts = newHostMatcher(cfg.DomainSuffixes(), cfg.Template())
+	s.probeTLS = s.transport.Clone()
+	s.probeTLS.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	s.probeH2C = newH2CTransport(s.transport)
+	s.routes = s.buildRoutes()
+	s.clientACLs = s.buildClientACLs()
+	s.inspector = newInspector(cfg.InspectSize, cfg.InspectBodyLimit)
+	s.faults = newFaultSet(cfg.Faults)
+	s.network = s.buildNetworkRules()
+	s.mirrors = newMirrorSet(cfg.Mirrors)
+	for _, rules := range s.routes {
+		for _, rt := range rules {
+			if rt.err != nil && logger != nil {
+				logger.Error("route configuration error", "route", rt.label(), "error", rt.err.Error())
+			}
+		}
 	}
 
 	go s.refreshIPs()
@@ -92,57 +124,134 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	r = r.WithContext(ctx)
 
 	w.Header().Set("X-Request-ID", requestID)
+	if !isReplay(r) {
+		r.Header.Del(replayHeader)
+	}
 
 	if s.isRootHost(r.Host) {
-		s.serveLandingPage(w, r)
+		if s.checkClient(w, r, requestID, nil) {
+			s.serveDashboard(w, r, requestID)
+		}
 		return
 	}
 
-	port, err := s.parseHost(r.Host)
+	rt, err := s.resolveRoute(r.Host, r.URL.Path)
+	if errors.Is(err, errUnknownRoute) {
+		resp := ErrorResponse{
+			Error:   err.Error(),
+			Hint:    "Use one of the known routes or " + s.hostFormat(),
+			Example: s.portURL(8000, ""),
+		}
+		if s.clientAllowed(remoteIP(r.RemoteAddr), requestListener(r), nil) {
+			resp.KnownRoutes = s.cfg.RouteNames()
+		}
+		s.writeError(w, r, requestID, http.StatusNotFound, resp)
+		s.logger.InvalidHost(requestID, r.Host, err.Error())
+		return
+	}
 	if err != nil {
 		s.handleError(w, r, requestID, http.StatusBadRequest, err.Error(),
-			"Use format: https://<port>.localhost",
-			"https://8000.localhost")
+			"Use format: "+s.hostFormat(),
+			s.portURL(8000, ""))
 		s.logger.InvalidHost(requestID, r.Host, err.Error())
 		return
 	}
+	if !s.checkClient(w, r, requestID, rt) {
+		return
+	}
 
-	if !s.cfg.IsPortAllowed(port) {
+	port := rt.port
+	if rt.usesPort() && !s.cfg.IsPortAllowed(port) {
 		s.handleError(w, r, requestID, http.StatusForbidden,
 			fmt.Sprintf("Port %d is not allowed", port),
 			"This port is either denied or outside the allowed range",
-			"https://8000.localhost")
+			s.portURL(8000, ""))
 		s.logger.PortDenied(requestID, port, "port in deny list or outside allow range")
 		return
 	}
 
+	if rt.usesPort() && !s.cfg.IsUpstreamAllowed(rt.host, port) {
+		s.handleError(w, r, requestID, http.StatusForbidden,
+			fmt.Sprintf("Upstream host %s is not allowed", rt.host),
+			"Add the host or its network to --allow-hosts",
+			"--allow-hosts 172.17.0.0/16")
+		s.logger.UpstreamDenied(requestID, rt.host, port, "host not in allow list")
+		return
+	}
+
+	if rt.err != nil {
+		s.handleError(w, r, requestID, http.StatusBadGateway,
+			"Upstream TLS configuration error",
+			rt.err.Error(),
+			"")
+		s.logger.ProxyError(requestID, port, rt.err)
+		return
+	}
+
 	rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	defer func() {
+		s.logger.LogRequest(ctx, logging.LogRequestParams{
+			Method:       r.Method,
+			Host:         r.Host,
+			Route:        rt.label(),
+			TargetPort:   port,
+			StatusCode:   rw.statusCode,
+			Latency:      time.Since(start),
+			BytesWritten: rw.bytesWritten,
+			Error:        rw.err,
+			ReplayOf:     r.Header.Get(replayHeader),
+			Fault:        rw.fault.String(),
+		})
+	}()
 
-	if isWebSocketRequest(r) {
-		s.handleWebSocket(rw, r, requestID, port)
-	} else {
-		s.handleHTTP(rw, r, requestID, port)
-	}
-
-	latency := time.Since(start)
-	s.logger.LogRequest(ctx, logging.LogRequestParams{
-		Method:       r.Method,
-		Host:         r.Host,
-		TargetPort:   port,
-		StatusCode:   rw.statusCode,
-		Latency:      latency,
-		BytesWritten: rw.bytesWritten,
-		Error:        rw.err,
-	})
+	switch {
+	case !s.shapeNetwork(rw, r, rt):
+	case rt.cors != nil && s.handleCORS(rw, r, requestID, rt):
+	case rt.auth != nil && !replayedOn(r, rt) && !s.authorize(rw, r, requestID, rt):
+	case s.injectFault(rw, r, requestID, rt):
+	case isWebSocketRequest(r) && rt.dir == "":
+		s.handleWebSocket(rw, r, requestID, rt)
+	default:
+		s.handleHTTP(rw, r, rt)
+	}
+}
+
+func (s *Server) resolveRoute(host, path string) (*route, error) {
+	host = strings.ToLower(host)
+	matches := s.hostMatcher().name.FindStringSubmatch(host)
+	if matches != nil {
+		name := matches[1]
+		if rt, ok := s.matchRoute(name, path); ok {
+			return rt, nil
+		}
+		if _, known := s.routes[name]; known {
+			return nil, fmt.Errorf("%w: no rule for %s%s", errUnknownRoute, name, path)
+		}
+	}
+
+	port, err := s.parseHost(host)
+	if err == nil {
+		return s.portRoute(port), nil
+	}
+
+	if matches != nil {
+		if _, numErr := strconv.Atoi(matches[1]); numErr != nil {
+			if rt, ok := s.matchRoute(s.cfg.DefaultRoute, path); ok {
+				return rt, nil
+			}
+			return nil, fmt.Errorf("%w: %s", errUnknownRoute, matches[1])
+		}
+	}
+
+	return nil, err
 }
 
 func (s *Server) parseHost(host string) (int, error) {
-	matches := hostPattern.FindStringSubmatch(host)
-	if matches == nil {
+	portStr, ok := s.hostMatcher().matchPort(strings.ToLower(host))
+	if !ok {
 		return 0, fmt.Errorf("invalid host format: %s", host)
 	}
 
-	portStr := matches[1]
 	port, err := strconv.Atoi(portStr)
 	if err != nil {
 		return 0, fmt.Errorf("invalid port number: %s", portStr)
@@ -156,7 +265,7 @@ func (s *Server) parseHost(host string) (int, error) {
 }
 
 func (s *Server) isRootHost(host string) bool {
-	if rootPattern.MatchString(host) {
+	if s.hostMatcher().root.MatchString(strings.ToLower(host)) {
 		return true
 	}
 
@@ -176,24 +285,40 @@ func (s *Server) isRootHost(host string) bool {
 	return false
 }
 
-func (s *Server) handleHTTP(w *responseWriter, r *http.Request, requestID string, port int) {
-	target, _ := url.Parse(fmt.Sprintf("http://127.0.0.1:%d", port))
+func (s *Server) handleHTTP(w *responseWriter, r *http.Request, rt *route) {
+	if rt.scheme == "h2c" {
+		rc := http.NewResponseController(w)
+		rc.EnableFullDuplex()
+		rc.SetWriteDeadline(time.Time{})
+	}
+	if s.inspector != nil {
+		requestID, _ := r.Context().Value(logging.RequestIDKey).(string)
+		var p *pendingExchange
+		p, r = s.inspector.start(w, r, rt, requestID)
+		defer s.inspector.finish(p, w)
+	}
+	if rt.dir != "" {
+		s.serveStatic(w, r, rt)
+		return
+	}
+	var finishMirror func()
+	if r, finishMirror = s.startMirror(w, r, rt); finishMirror != nil {
+		defer finishMirror()
+	}
+	rt.proxy.ServeHTTP(w, withPublicHost(r))
+}
 
-	proxy := &httputil.ReverseProxy{
-		Director: func(req *http.Request) {
-			req.URL.Scheme = target.Scheme
-			req.URL.Host = target.Host
-			req.Host = r.Host
+func (s *Server) newReverseProxy(rt *route) *httputil.ReverseProxy {
+	target, port := rt.target, rt.port
+	var flushInterval time.Duration
+	if rt.scheme == "h2c" {
+		flushInterval = -1
+	}
 
-			if clientIP, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
-				if prior := req.Header.Get("X-Forwarded-For"); prior != "" {
-					clientIP = prior + ", " + clientIP
-				}
-				req.Header.Set("X-Forwarded-For", clientIP)
-			}
-			req.Header.Set("X-Forwarded-Proto", "https")
-			req.Header.Set("X-Forwarded-Host", r.Host)
-			req.Header.Set("X-Request-ID", requestID)
+	return &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			requestID, _ := req.Context().Value(logging.RequestIDKey).(string)
+			rt.direct(req)
 
 			s.logger.Debug("proxying request",
 				"request_id", requestID,
@@ -202,42 +327,105 @@ func (s *Server) handleHTTP(w *responseWriter, r *http.Request, requestID string
 				"target", target.String(),
 			)
 		},
-		Transport: s.transport,
+		Transport:     rt.transport,
+		FlushInterval: flushInterval,
 		ErrorHandler: func(rw http.ResponseWriter, req *http.Request, err error) {
+			requestID, _ := req.Context().Value(logging.RequestIDKey).(string)
 			s.logger.ProxyError(requestID, port, err)
-			w.err = err
-
-			errMsg := "Backend service unavailable"
-			hint := fmt.Sprintf("Make sure a service is running on port %d", port)
-
-			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-				errMsg = "Request timed out"
-				hint = "The backend service took too long to respond"
-			} else if strings.Contains(err.Error(), "connection refused") {
-				errMsg = "Connection refused"
-				hint = fmt.Sprintf("No service is listening on port %d", port)
+			s.reprobeScheme(rt, err)
+			if w, ok := rw.(*responseWriter); ok {
+				w.err = err
+			}
+			if isGRPCContentType(req.Header.Get("Content-Type")) {
+				writeGRPCUnavailable(rw, fmt.Sprintf("httpsify: no gRPC service reachable on %s", rt.upstream()))
+				return
 			}
 
-			s.writeJSONError(rw, http.StatusBadGateway, errMsg, hint, "")
+			errMsg, hint := describeProxyError(err, rt)
+			s.handleError(rw, req, requestID, http.StatusBadGateway, errMsg, hint, "")
 		},
 		ModifyResponse: func(resp *http.Response) error {
+			publicHost := requestPublicHost(resp.Request)
+			// Capture before the rewrites below so both mirror sides are compared raw.
+			if m, ok := resp.Request.Context().Value(mirrorKey{}).(*mirrorPrimary); ok {
+				m.capture(resp)
+			}
+			if rt.cors != nil {
+				stripCORSHeaders(resp.Header)
+			}
+			if rt.identity != "" {
+				rt.restoreIdentity(resp, publicHost)
+			}
+			if u := s.newURLRewriter(rt, resp.Request, publicHost); u != nil {
+				u.rewriteHeaders(resp.Header)
+				if rt.rewrite {
+					if err := u.rewriteBody(resp); err != nil {
+						return err
+					}
+				}
+			}
+			if rt.headers != nil {
+				applyHeaderOps(resp.Header, rt.headers.Response, rt.headerVars(resp.Request, publicHost))
+			}
 			return nil
 		},
 	}
+}
+
+func (rt *route) direct(req *http.Request) {
+	requestID, _ := req.Context().Value(logging.RequestIDKey).(string)
+	publicHost := req.Host
+
+	rt.rewritePath(req)
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	req.Host = publicHost
+	if rt.identity != "" {
+		rt.applyIdentity(req, publicHost)
+	}
 
-	proxy.ServeHTTP(w, r)
+	if clientIP, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		if prior := req.Header.Get("X-Forwarded-For"); prior != "" {
+			clientIP = prior + ", " + clientIP
+		}
+		req.Header.Set("X-Forwarded-For", clientIP)
+	}
+	proto := "https"
+	if isPlainHTTP(req) {
+		proto = "http"
+	}
+	req.Header.Set("X-Forwarded-Proto", proto)
+	req.Header.Set("X-Forwarded-Host", publicHost)
+	if rt.strip && rt.path != "" {
+		req.Header.Set("X-Forwarded-Prefix", rt.path)
+	}
+	req.Header.Set("X-Request-ID", requestID)
+	if rt.rewrite {
+		if acceptsGzip(req.Header) {
+			req.Header.Set("Accept-Encoding", "gzip")
+		} else {
+			req.Header.Del("Accept-Encoding")
+		}
+	}
+	if rt.headers != nil {
+		applyHeaderOps(req.Header, rt.headers.Request, rt.headerVars(req, publicHost))
+	}
 }
 
-func (s *Server) handleWebSocket(w *responseWriter, r *http.Request, requestID string, port int) {
+func (s *Server) handleWebSocket(w *responseWriter, r *http.Request, requestID string, rt *route) {
+	port := rt.port
 	s.logger.WebSocketUpgrade(requestID, port)
 
-	backendAddr := fmt.Sprintf("127.0.0.1:%d", port)
-	backendConn, err := net.DialTimeout("tcp", backendAddr, time.Duration(s.cfg.DialTimeout)*time.Second)
+	var backendConn net.Conn
+	err := s.hold(r.Context(), rt, backendDown, func() (err error) {
+		backendConn, err = s.dialUpstream(r.Context(), rt)
+		return err
+	})
 	if err != nil {
 		s.logger.ProxyError(requestID, port, err)
 		s.handleError(w.ResponseWriter, r, requestID, http.StatusBadGateway,
 			"Failed to connect to backend",
-			fmt.Sprintf("Make sure a service is running on port %d", port),
+			fmt.Sprintf("Make sure a service is running on %s", rt.upstream()),
 			"")
 		return
 	}
@@ -263,17 +451,39 @@ func (s *Server) handleWebSocket(w *responseWriter, r *http.Request, requestID s
 	}
 	defer clientConn.Close()
 
+	publicHost := r.Host
+	rt.rewritePath(r)
+	if rt.identity != "" {
+		rt.applyIdentity(r, publicHost)
+	}
+	if rt.headers != nil {
+		applyHeaderOps(r.Header, rt.headers.Request, rt.headerVars(r, publicHost))
+	}
 	if err := r.Write(backendConn); err != nil {
 		s.logger.ProxyError(requestID, port, fmt.Errorf("failed to write request to backend: %w", err))
 		return
 	}
+	if w.fault != nil && w.fault.wsDrop {
+		drop := time.AfterFunc(w.fault.wsAfter, func() {
+			clientConn.Close()
+			backendConn.Close()
+		})
+		defer drop.Stop()
+	}
+
+	var toClient io.Writer = clientConn
+	var fromClient io.Reader = clientConn
+	if p := w.network; p != nil {
+		toClient = &throttledWriter{w: clientConn, s: newShaper(r.Context(), p.DownKbps, p)}
+		fromClient = &throttledReader{r: clientConn, s: newShaper(r.Context(), p.UpKbps, p)}
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(2)
 
 	go func() {
 		defer wg.Done()
-		io.Copy(clientConn, backendConn)
+		io.Copy(toClient, backendConn)
 		clientConn.Close()
 	}()
 
@@ -282,7 +492,7 @@ func (s *Server) handleWebSocket(w *responseWriter, r *http.Request, requestID s
 		if clientBuf.Reader.Buffered() > 0 {
 			io.CopyN(backendConn, clientBuf, int64(clientBuf.Reader.Buffered()))
 		}
-		io.Copy(backendConn, clientConn)
+		io.Copy(backendConn, fromClient)
 		backendConn.Close()
 	}()
 
@@ -296,19 +506,24 @@ func isWebSocketRequest(r *http.Request) bool {
 }
 
 func (s *Server) handleError(w http.ResponseWriter, r *http.Request, requestID string, statusCode int, errMsg, hint, example string) {
-	s.writeJSONError(w, statusCode, errMsg, hint, example)
+	s.writeError(w, r, requestID, statusCode, ErrorResponse{
+		Error:   errMsg,
+		Hint:    hint,
+		Example: example,
+	})
 }
 
 func (s *Server) writeJSONError(w http.ResponseWriter, statusCode int, errMsg, hint, example string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-
-	resp := ErrorResponse{
+	s.writeErrorResponse(w, statusCode, ErrorResponse{
 		Error:   errMsg,
 		Hint:    hint,
 		Example: example,
-	}
+	})
+}
 
+func (s *Server) writeErrorResponse(w http.ResponseWriter, statusCode int, resp ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(resp)
 }
 
@@ -323,6 +538,10 @@ type responseWriter struct {
 	bytesWritten int64
 	err          error
 	wroteHeader  bool
+	capture      *bodyCapture
+	fault        *faultPlan
+	network      *config.NetworkProfile
+	downlink     *throttledWriter
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -337,8 +556,27 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	if !rw.wroteHeader {
 		rw.WriteHeader(http.StatusOK)
 	}
-	n, err := rw.ResponseWriter.Write(b)
+	truncated := false
+	if rw.fault != nil && rw.fault.truncate >= 0 {
+		if room := int64(rw.fault.truncate) - rw.bytesWritten; room < int64(len(b)) {
+			b, truncated = b[:max(room, 0)], true
+		}
+	}
+	var n int
+	var err error
+	if rw.downlink != nil {
+		n, err = rw.downlink.Write(b)
+	} else {
+		n, err = rw.ResponseWriter.Write(b)
+	}
 	rw.bytesWritten += int64(n)
+	if rw.capture != nil {
+		rw.capture.Write(b[:n])
+	}
+	if truncated {
+		rw.Flush()
+		panic(http.ErrAbortHandler)
+	}
 	return n, err
 }
 
@@ -349,6 +587,10 @@ func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return nil, nil, fmt.Errorf("hijacking not supported")
 }
 
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func (rw *responseWriter) Flush() {
 	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
 		flusher.Flush()
@@ -357,7 +599,10 @@ func (rw *responseWriter) Flush() {
 
 type ServiceInfo struct {
 	Port        int               `json:"port"`
+	Socket      string            `json:"socket,omitempty"`
 	IsWeb       bool              `json:"is_web"`
+	TLS         bool              `json:"tls,omitempty"`
+	StatusCode  int               `json:"status_code,omitempty"`
 	Server      string            `json:"server,omitempty"`
 	ContentType string            `json:"content_type,omitempty"`
 	Protocol    string            `json:"protocol,omitempty"`
@@ -451,15 +696,7 @@ func (s *Server) GetListeningPorts() []ServiceInfo {
 
 			inode, _ := strconv.ParseUint(inodeStr, 10, 64)
 
-			proxyPort := 443
-			if strings.Contains(s.cfg.ListenAddr, ":") {
-				parts := strings.Split(s.cfg.ListenAddr, ":")
-				if p, err := strconv.Atoi(parts[len(parts)-1]); err == nil {
-					proxyPort = p
-				}
-			}
-
-			if int(port) == proxyPort {
+			if s.cfg.IsListenPort(int(port)) {
 				continue
 			}
 
@@ -588,8 +825,50 @@ func (s *Server) probeService(port int) ServiceInfo {
 		return info
 	}
 
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	info = s.probeURL(info, "http://"+addr)
+	if info.Protocol != "" && info.StatusCode != http.StatusBadRequest {
+		s.portSchemes.Store(port, "http")
+		return info
+	}
+
+	if s.speaksTLS(addr) {
+		info = s.probeURL(ServiceInfo{Port: port, TLS: true}, "https://"+addr)
+		info.TLS = true
+		s.portSchemes.Store(port, "https")
+	} else if h2c, ok := s.probeH2CService(port); ok {
+		info = h2c
+		s.portSchemes.Store(port, "h2c")
+	} else {
+		s.portSchemes.Store(port, "http")
+	}
+	return info
+}
+
+func (s *Server) speaksTLS(addr string) bool {
+	conn, err := net.DialTimeout("tcp", addr, 250*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(250 * time.Millisecond))
+	tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+	return tlsConn.Handshake() == nil
+}
+
+func (s *Server) probeURL(info ServiceInfo, url string) ServiceInfo {
+	if info.Headers == nil {
+		info.Headers = make(map[string]string)
+	}
+
+	transport := s.transport
+	if strings.HasPrefix(url, "https://") {
+		transport = s.probeTLS
+	}
+
 	client := &http.Client{
-		Transport: s.transport,
+		Transport: transport,
 		Timeout:   250 * time.Millisecond,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
@@ -597,7 +876,6 @@ func (s *Server) probeService(port int) ServiceInfo {
 	}
 
 	start := time.Now()
-	url := fmt.Sprintf("http://127.0.0.1:%d", port)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return info
@@ -617,6 +895,7 @@ func (s *Server) probeService(port int) ServiceInfo {
 	}
 
 	info.Protocol = resp.Proto
+	info.StatusCode = resp.StatusCode
 	info.Server = resp.Header.Get("Server")
 	info.ContentType = resp.Header.Get("Content-Type")
 
@@ -1,12 +1,14 @@
 package proxy
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/imcanugur/httpsify/internal/config"
+	"github.com/imcanugur/httpsify/internal/logging"
 )
 
 func TestParseHost(t *testing.T) {
@@ -206,22 +208,25 @@ func TestHostPatternRegex(t *testing.T) {
 		{"8000.", false, ""},
 	}
 
+	hostPattern := newHostMatcher(config.DefaultSuffixes, config.DefaultHostTemplate).port
 	for _, tt := range tests {
 		t.Run(tt.host, func(t *testing.T) {
 			matches := hostPattern.FindStringSubmatch(tt.host)
 			if (matches != nil) != tt.match {
-				t.Errorf("hostPattern.FindStringSubmatch(%q) match = %v, want %v", tt.host, matches != nil, tt.match)
+				t.Errorf("port pattern FindStringSubmatch(%q) match = %v, want %v", tt.host, matches != nil, tt.match)
 			}
 			if tt.match && matches[1] != tt.port {
-				t.Errorf("hostPattern.FindStringSubmatch(%q) port = %q, want %q", tt.host, matches[1], tt.port)
+				t.Errorf("port pattern FindStringSubmatch(%q) port = %q, want %q", tt.host, matches[1], tt.port)
 			}
 		})
 	}
 }
 
 func TestServeLandingPage(t *testing.T) {
-	s := NewServer(config.DefaultConfig(), nil)
+	cfg := config.DefaultConfig()
+	s := newTestServer(t, cfg)
 	req := httptest.NewRequest("GET", "https://localhost/", nil)
+	req.RemoteAddr = "127.0.0.1:54321"
 	rr := httptest.NewRecorder()
 
 	s.ServeHTTP(rr, req)
@@ -239,3 +244,15 @@ func TestServeLandingPage(t *testing.T) {
 		t.Errorf("handler returned body without 'httpsify' title")
 	}
 }
+
+func newTestServer(t *testing.T, cfg *config.Config) *Server {
+	t.Helper()
+	cfg.StateDir = t.TempDir()
+	return NewServer(cfg, logging.NewLogger(false, false))
+}
+
+func newLocalRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	req.RemoteAddr = "127.0.0.1:54321"
+	return req
+}
@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type plainHTTPKey struct{}
+
+func (s *Server) HTTPHandler() http.Handler {
+	return http.HandlerFunc(s.servePlainHTTP)
+}
+
+func (s *Server) servePlainHTTP(w http.ResponseWriter, r *http.Request) {
+	r = r.WithContext(context.WithValue(r.Context(), plainHTTPKey{}, true))
+	rt, err := s.resolveRoute(r.Host, r.URL.Path)
+	if err == nil && rt.allowHTTP {
+		s.ServeHTTP(w, r)
+		return
+	}
+
+	if !s.checkClient(w, r, s.generateRequestID(), rt) {
+		return
+	}
+
+	http.Redirect(w, r, s.httpsURL(r), http.StatusPermanentRedirect)
+}
+
+func (s *Server) httpsURL(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = strings.Trim(r.Host, "[]")
+	}
+	if port := s.cfg.ListenPort(); port != 443 {
+		host = net.JoinHostPort(host, strconv.Itoa(port))
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return "https://" + host + r.URL.RequestURI()
+}
+
+func isPlainHTTP(r *http.Request) bool {
+	plain, _ := r.Context().Value(plainHTTPKey{}).(bool)
+	return plain
+}
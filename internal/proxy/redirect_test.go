@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestPlainHTTPRedirect(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Forwarded-Proto")))
+	}))
+	defer backend.Close()
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{
+		{Name: "legacy", Port: port, AllowHTTP: true},
+		{Name: "app", Port: port},
+	}
+	s := newTestServer(t, cfg)
+	h := s.HTTPHandler()
+
+	req := newLocalRequest("POST", "http://app.localhost/login?next=%2Fhome", nil)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusPermanentRedirect {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusPermanentRedirect)
+	}
+	if got, want := rr.Header().Get("Location"), "https://app.localhost/login?next=%2Fhome"; got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+
+	cfg.ListenAddr = ":8443"
+	req = newLocalRequest("GET", "http://3000.localhost:8080/", nil)
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if got, want := rr.Header().Get("Location"), "https://3000.localhost:8443/"; got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+
+	req = newLocalRequest("GET", "http://legacy.localhost/", nil)
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || rr.Body.String() != "http" {
+		t.Errorf("pass-through = %d %q, want 200 \"http\"", rr.Code, rr.Body.String())
+	}
+}
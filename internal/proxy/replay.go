@@ -0,0 +1,247 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const replayHeader = "X-Httpsify-Replay"
+
+var (
+	errExchangeNotFound = errors.New("exchange not found")
+	errTruncatedBody    = errors.New("captured request body was truncated")
+	errReplayAborted    = errors.New("replay aborted before the response completed")
+)
+
+type replayKey struct{}
+
+type ReplayRequest struct {
+	Route   string            `json:"route,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	Path    string            `json:"path,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    *string           `json:"body,omitempty"`
+}
+
+type ReplayResult struct {
+	ID       string       `json:"id"`
+	ReplayOf string       `json:"replay_of"`
+	Status   int          `json:"status"`
+	Headers  http.Header  `json:"headers"`
+	Body     CapturedBody `json:"body"`
+}
+
+func isReplay(r *http.Request) bool {
+	return r.Context().Value(replayKey{}) != nil
+}
+
+func replayedOn(r *http.Request, rt *route) bool {
+	route, ok := r.Context().Value(replayKey{}).(string)
+	return ok && route == exchangeRoute(rt)
+}
+
+type replayRecorder struct {
+	header http.Header
+	status int
+	body   bodyCapture
+}
+
+func (rec *replayRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *replayRecorder) WriteHeader(code int) {
+	if rec.status == 0 {
+		rec.status = code
+	}
+}
+
+func (rec *replayRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(b)
+}
+
+func (rec *replayRecorder) Flush() {}
+
+func (s *Server) replayHost(route string) (string, error) {
+	if port, err := strconv.Atoi(route); err == nil {
+		return s.cfg.PortHost(port, ""), nil
+	}
+	name, _, _ := strings.Cut(route, "/")
+	if len(s.cfg.FindRoutes(name)) == 0 {
+		return "", fmt.Errorf("%w: %s", errUnknownRoute, route)
+	}
+	return s.cfg.RouteHost(name), nil
+}
+
+func (s *Server) Replay(ctx context.Context, id string, edit ReplayRequest) (*ReplayResult, error) {
+	var src *Exchange
+	if s.inspector != nil {
+		src = s.inspector.get(id)
+	}
+	if src == nil {
+		return nil, fmt.Errorf("%w: %s", errExchangeNotFound, id)
+	}
+
+	target, err := http.NewRequest(src.Method, src.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	method, host, path := src.Method, target.Host, target.URL.RequestURI()
+	if edit.Method != "" {
+		method = strings.ToUpper(edit.Method)
+	}
+	if edit.Route != "" {
+		if host, err = s.replayHost(edit.Route); err != nil {
+			return nil, err
+		}
+	}
+	if edit.Path != "" {
+		path = "/" + strings.TrimPrefix(edit.Path, "/")
+	}
+
+	var body []byte
+	switch {
+	case edit.Body != nil:
+		body = []byte(*edit.Body)
+	case src.RequestBody.Truncated:
+		return nil, fmt.Errorf("%w: send an edited body to replay it", errTruncatedBody)
+	case src.RequestBody.Binary:
+		body, _ = base64.StdEncoding.DecodeString(src.RequestBody.Base64)
+	default:
+		body = []byte(src.RequestBody.Text)
+	}
+
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, replayKey{}, src.Route), method, target.URL.Scheme+"://"+host+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = src.RequestHeaders.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	for name, value := range edit.Headers {
+		if value == "" {
+			req.Header.Del(name)
+		} else {
+			req.Header.Set(name, value)
+		}
+	}
+	req.Header.Del("Content-Length")
+	req.Header.Set(replayHeader, id)
+	req.RequestURI = req.URL.RequestURI()
+	req.RemoteAddr = "127.0.0.1:0"
+
+	rec := &replayRecorder{header: http.Header{}, body: bodyCapture{limit: s.cfg.InspectBodyLimit}}
+	if s.serveAbortable(rec, req) {
+		return nil, fmt.Errorf("%w: %s", errReplayAborted, rec.header.Get("X-Request-ID"))
+	}
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return &ReplayResult{
+		ID:       rec.header.Get("X-Request-ID"),
+		ReplayOf: id,
+		Status:   rec.status,
+		Headers:  rec.header,
+		Body:     rec.body.body(rec.header),
+	}, nil
+}
+
+func (s *Server) serveAbortable(w http.ResponseWriter, r *http.Request) (aborted bool) {
+	defer func() {
+		if p := recover(); p != nil {
+			if p != http.ErrAbortHandler {
+				panic(p)
+			}
+			aborted = true
+		}
+	}()
+	s.ServeHTTP(w, r)
+	return false
+}
+
+func (s *Server) writeReplayError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errReplayAborted):
+		s.writeJSONError(w, http.StatusBadGateway, err.Error(), "A fault rule may have reset or truncated the response", "")
+	case errors.Is(err, errExchangeNotFound):
+		s.writeJSONError(w, http.StatusNotFound, err.Error(), "Only recent exchanges are kept", "")
+	case errors.Is(err, errTruncatedBody):
+		s.writeJSONError(w, http.StatusConflict, err.Error(), "Raise --inspect-body to capture larger bodies", "")
+	default:
+		s.writeJSONError(w, http.StatusBadRequest, err.Error(), "", "")
+	}
+}
+
+func (s *Server) serveReplayAPI(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		s.writeJSONError(w, http.StatusMethodNotAllowed, "Use POST to replay an exchange", "", "")
+		return
+	}
+	var edit ReplayRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+			s.writeJSONError(w, http.StatusBadRequest, "Invalid replay request", err.Error(), `{"method": "POST", "headers": {"X-Debug": "1"}}`)
+			return
+		}
+	}
+	result, err := s.Replay(r.Context(), id, edit)
+	if err != nil {
+		s.writeReplayError(w, err)
+		return
+	}
+	s.writeJSON(w, result)
+}
+
+func (s *Server) serveReplayForm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeJSONError(w, http.StatusMethodNotAllowed, "Use POST to replay an exchange", "", "")
+		return
+	}
+	if s.inspector == nil {
+		s.writeJSONError(w, http.StatusNotFound, "Traffic inspector is disabled", "Start httpsify with --inspect set above 0", "--inspect 100")
+		return
+	}
+	id := r.PostFormValue("id")
+	edit := ReplayRequest{
+		Route:  strings.TrimSpace(r.PostFormValue("route")),
+		Method: strings.TrimSpace(r.PostFormValue("method")),
+		Path:   strings.TrimSpace(r.PostFormValue("path")),
+	}
+	if _, ok := r.PostForm["body"]; ok {
+		body := r.PostFormValue("body")
+		edit.Body = &body
+	}
+	if _, ok := r.PostForm["headers"]; ok {
+		edit.Headers = map[string]string{}
+		if src := s.inspector.get(id); src != nil {
+			for name := range src.RequestHeaders {
+				edit.Headers[name] = ""
+			}
+		}
+		for _, line := range strings.Split(r.PostFormValue("headers"), "\n") {
+			name, value, ok := strings.Cut(line, ":")
+			if name = strings.TrimSpace(name); ok && name != "" {
+				edit.Headers[http.CanonicalHeaderKey(name)] = strings.TrimSpace(value)
+			}
+		}
+	}
+
+	result, err := s.Replay(r.Context(), id, edit)
+	if err != nil {
+		s.writeReplayError(w, err)
+		return
+	}
+	http.Redirect(w, r, "/inspector?id="+url.QueryEscape(result.ID), http.StatusSeeOther)
+}
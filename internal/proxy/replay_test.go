@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestReplay(t *testing.T) {
+	type seen struct{ method, path, replay, debug, body string }
+	requests := make(chan seen, 10)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- seen{r.Method, r.URL.RequestURI(), r.Header.Get(replayHeader), r.Header.Get("X-Debug"), string(body)}
+		fmt.Fprintf(w, "got %s", body)
+	}))
+	defer backend.Close()
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.InspectBodyLimit = 16
+	cfg.Routes = []config.Route{
+		{Name: "shop", Port: port, Auth: &config.RouteAuth{Tokens: []string{"s3cret"}}},
+		{Name: "shadow", Port: port},
+		{Name: "open", Port: port},
+	}
+	s := newTestServer(t, cfg)
+
+	send := func(body string) string {
+		req := newLocalRequest("POST", "https://shop.localhost/orders?x=1", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		req.Header.Set("X-Debug", "0")
+		req.Header.Set(replayHeader, "spoofed")
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		if got := <-requests; got.replay != "" {
+			t.Errorf("client-supplied %s = %q reached the backend", replayHeader, got.replay)
+		}
+		return rr.Header().Get("X-Request-ID")
+	}
+	id := send("small")
+	longID := send("a body longer than the capture limit")
+
+	body := "edited"
+	tests := []struct {
+		name string
+		id   string
+		edit ReplayRequest
+		want seen
+		err  error
+	}{
+		{"unchanged", id, ReplayRequest{}, seen{"POST", "/orders?x=1", id, "0", "small"}, nil},
+		{"edited", id, ReplayRequest{Method: "put", Path: "/v2", Headers: map[string]string{"X-Debug": "1"}, Body: &body}, seen{"PUT", "/v2", id, "1", "edited"}, nil},
+		{"removed header", id, ReplayRequest{Route: "shadow", Headers: map[string]string{"X-Debug": ""}}, seen{"POST", "/orders?x=1", id, "", "small"}, nil},
+		{"by port", id, ReplayRequest{Route: strconv.Itoa(port)}, seen{"POST", "/orders?x=1", id, "0", "small"}, nil},
+		{"truncated", longID, ReplayRequest{}, seen{}, errTruncatedBody},
+		{"truncated edited", longID, ReplayRequest{Body: &body}, seen{"POST", "/orders?x=1", longID, "0", "edited"}, nil},
+		{"unknown", "nope", ReplayRequest{}, seen{}, errExchangeNotFound},
+		{"unknown route", id, ReplayRequest{Route: "nope"}, seen{}, errUnknownRoute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := s.Replay(context.Background(), tt.id, tt.edit)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Replay() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Replay() error = %v", err)
+			}
+			if got := <-requests; got != tt.want {
+				t.Errorf("backend saw %+v, want %+v", got, tt.want)
+			}
+			if result.Status != http.StatusOK || result.ReplayOf != tt.id || result.Body.Text != "got "+tt.want.body {
+				t.Errorf("result = %+v", result)
+			}
+			if e := s.inspector.get(result.ID); e == nil || e.RequestHeaders.Get(replayHeader) != tt.id {
+				t.Errorf("replayed exchange %s not captured with %s", result.ID, replayHeader)
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://open.localhost/orders", nil))
+	<-requests
+	openID := rr.Header().Get("X-Request-ID")
+	if result, err := s.Replay(context.Background(), openID, ReplayRequest{Route: "shop"}); err != nil || result.Status != http.StatusUnauthorized {
+		t.Errorf("replay of an open capture onto an auth route = %+v, %v, want %d", result, err, http.StatusUnauthorized)
+	}
+
+	api := "https://localhost/api/exchanges/" + id + "/replay"
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("POST", api, strings.NewReader(`{"method":"PATCH"}`)))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("replay without CSRF = %d, want %d", rr.Code, http.StatusForbidden)
+	}
+	token, _ := s.DashboardToken()
+	req := newLocalRequest("POST", api, strings.NewReader(`{"method":"PATCH"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	var result ReplayResult
+	if err := json.NewDecoder(rr.Body).Decode(&result); err != nil || rr.Code != http.StatusOK || result.ReplayOf != id {
+		t.Errorf("API replay = %d %+v, %v", rr.Code, result, err)
+	}
+	if got := <-requests; got.method != "PATCH" {
+		t.Errorf("API replay method = %s, want PATCH", got.method)
+	}
+
+	cookie, csrf := loopbackCSRF(s)
+	form := url.Values{"csrf_token": {csrf}, "id": {id}, "method": {"POST"}, "headers": {"X-Debug: form"}, "body": {"from form"}}
+	req = newLocalRequest("POST", "https://localhost/inspector/replay", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	if rr.Code != http.StatusSeeOther || !strings.HasPrefix(rr.Header().Get("Location"), "/inspector?id=") {
+		t.Errorf("form replay = %d %s, want redirect to the new exchange", rr.Code, rr.Header().Get("Location"))
+	}
+	if got := <-requests; got.debug != "form" || got.body != "from form" {
+		t.Errorf("form replay = %+v", got)
+	}
+}
@@ -0,0 +1,262 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+var rewritableTypes = map[string]bool{
+	"text/html":                true,
+	"application/xhtml+xml":    true,
+	"text/css":                 true,
+	"text/javascript":          true,
+	"application/javascript":   true,
+	"application/x-javascript": true,
+}
+
+type urlRewriter struct {
+	olds   [][]byte
+	news   [][]byte
+	first  [256]bool
+	hosts  map[string]bool
+	secure bool
+}
+
+func (s *Server) newURLRewriter(rt *route, req *http.Request, publicHost string) *urlRewriter {
+	if rt.socket != "" && rt.identity == "" {
+		return nil
+	}
+
+	secure := !isPlainHTTP(req)
+	public := "https://" + publicHost
+	publicWS := "wss://" + publicHost
+	if !secure {
+		public, publicWS = "http://"+publicHost, "ws://"+publicHost
+	}
+	if rt.strip && rt.path != "" {
+		public += rt.path
+		publicWS += rt.path
+	}
+
+	hosts := []string{rt.host}
+	if config.IsLoopbackHost(rt.host) {
+		hosts = []string{"127.0.0.1", "localhost", "::1", "0.0.0.0"}
+	}
+
+	scheme, wsScheme, defaultPort := "http", "ws", 80
+	if rt.tlsConfig != nil {
+		scheme, wsScheme, defaultPort = "https", "wss", 443
+	}
+
+	u := &urlRewriter{hosts: make(map[string]bool), secure: secure}
+	if rt.identity != "" {
+		host, _, err := net.SplitHostPort(rt.identity)
+		if err != nil {
+			host = rt.identity
+		}
+		u.hosts[strings.ToLower(strings.Trim(host, "[]"))] = true
+		u.add(scheme+"://"+rt.identity, public)
+		u.add(wsScheme+"://"+rt.identity, publicWS)
+	}
+	if rt.socket != "" {
+		return u
+	}
+	for _, host := range hosts {
+		u.hosts[host] = true
+		addrs := []string{net.JoinHostPort(host, strconv.Itoa(rt.port))}
+		if rt.port == defaultPort {
+			if strings.Contains(host, ":") {
+				host = "[" + host + "]"
+			}
+			addrs = append(addrs, host)
+		}
+		for _, addr := range addrs {
+			u.add(scheme+"://"+addr, public)
+			u.add(wsScheme+"://"+addr, publicWS)
+		}
+	}
+	return u
+}
+
+func (u *urlRewriter) add(old, new string) {
+	u.first[old[0]] = true
+	u.olds = append(u.olds, []byte(old))
+	u.news = append(u.news, []byte(new))
+}
+
+func (u *urlRewriter) rewrite(dst *bytes.Buffer, buf []byte, eof bool) int {
+	i := 0
+	for i < len(buf) {
+		matched, partial := u.matchAt(buf[i:], eof)
+		if partial {
+			break
+		}
+		if matched >= 0 {
+			dst.Write(u.news[matched])
+			i += len(u.olds[matched])
+			continue
+		}
+		dst.WriteByte(buf[i])
+		i++
+	}
+	return i
+}
+
+func (u *urlRewriter) matchAt(b []byte, eof bool) (int, bool) {
+	if !u.first[b[0]] {
+		return -1, false
+	}
+	for n, old := range u.olds {
+		if len(b) <= len(old) {
+			if bytes.HasPrefix(old, b) && !eof {
+				return -1, true
+			}
+			if len(b) < len(old) || !bytes.Equal(b, old) {
+				continue
+			}
+			return n, false
+		}
+		if bytes.HasPrefix(b, old) && !isHostChar(b[len(old)]) {
+			return n, false
+		}
+	}
+	return -1, false
+}
+
+func isHostChar(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '.' || c == '-' || c == ':'
+}
+
+func (u *urlRewriter) rewriteString(s string) string {
+	var dst bytes.Buffer
+	u.rewrite(&dst, []byte(s), true)
+	return dst.String()
+}
+
+func (u *urlRewriter) rewriteHeaders(h http.Header) {
+	for _, name := range []string{"Location", "Content-Location", "Refresh"} {
+		if v := h.Get(name); v != "" {
+			h.Set(name, u.rewriteString(v))
+		}
+	}
+
+	cookies := h.Values("Set-Cookie")
+	for i, cookie := range cookies {
+		cookies[i] = u.rewriteCookie(cookie)
+	}
+}
+
+func (u *urlRewriter) rewriteCookie(cookie string) string {
+	parts := strings.Split(cookie, ";")
+	kept := parts[:1]
+	secure := false
+	for _, attr := range parts[1:] {
+		name, value, _ := strings.Cut(strings.TrimSpace(attr), "=")
+		switch strings.ToLower(name) {
+		case "domain":
+			if u.hosts[strings.Trim(strings.ToLower(value), ".[]")] {
+				continue
+			}
+		case "secure":
+			secure = true
+		}
+		kept = append(kept, attr)
+	}
+	if u.secure && !secure {
+		kept = append(kept, " Secure")
+	}
+	return strings.Join(kept, ";")
+}
+
+func (u *urlRewriter) rewriteBody(resp *http.Response) error {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !rewritableTypes[mediaType] || resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+
+	encoding := strings.ToLower(resp.Header.Get("Content-Encoding"))
+	if encoding != "" && encoding != "identity" && encoding != "gzip" {
+		return nil
+	}
+
+	body := resp.Body
+	var src io.Reader = body
+	if encoding == "gzip" {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return err
+		}
+		src = gz
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer body.Close()
+		var dst io.Writer = pw
+		var gz *gzip.Writer
+		if encoding == "gzip" {
+			gz = gzip.NewWriter(pw)
+			dst = gz
+		}
+		err := u.copy(dst, src)
+		if gz != nil && err == nil {
+			err = gz.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	resp.Body = pr
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		resp.Header.Set("ETag", "W/"+etag)
+	}
+	return nil
+}
+
+func (u *urlRewriter) copy(dst io.Writer, src io.Reader) error {
+	var pending []byte
+	var out bytes.Buffer
+	chunk := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(chunk)
+		pending = append(pending, chunk[:n]...)
+		eof := err == io.EOF
+		if err != nil && !eof {
+			return err
+		}
+
+		consumed := u.rewrite(&out, pending, eof)
+		pending = append(pending[:0], pending[consumed:]...)
+		if out.Len() > 0 {
+			if _, werr := dst.Write(out.Bytes()); werr != nil {
+				return werr
+			}
+			if f, ok := dst.(interface{ Flush() error }); ok {
+				f.Flush()
+			}
+			out.Reset()
+		}
+		if eof {
+			return nil
+		}
+	}
+}
+
+func acceptsGzip(h http.Header) bool {
+	for _, enc := range strings.Split(h.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
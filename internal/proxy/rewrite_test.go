@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestUpstreamURLRewriting(t *testing.T) {
+	var origin string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Header().Set("Refresh", "5; url="+origin+"/home")
+			w.Header().Add("Set-Cookie", "sid=1; Domain=localhost; Path=/; HttpOnly")
+			w.Header().Add("Set-Cookie", "theme=dark; Domain=example.com; Secure")
+			http.Redirect(w, r, origin+"/dashboard?x=1", http.StatusFound)
+		case "/page":
+			body := `<a href="` + origin + `/a">a</a><script src="http://localhost:` + strings.TrimPrefix(origin, "http://127.0.0.1:") + `/app.js"></script>` +
+				`<script>new WebSocket("ws://127.0.0.1:` + strings.TrimPrefix(origin, "http://127.0.0.1:") + `/hmr")</script>` +
+				`<a href="` + origin + `0/other">keep</a>`
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("ETag", `"v1"`)
+			if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+				w.Header().Set("Content-Encoding", "gzip")
+				gz := gzip.NewWriter(w)
+				gz.Write([]byte(body))
+				gz.Close()
+				return
+			}
+			w.Write([]byte(body))
+		}
+	}))
+	defer backend.Close()
+	origin = backend.URL
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{
+		{Name: "app", Port: port},
+		{Name: "web", Port: port, RewriteBody: true},
+	}
+	s := newTestServer(t, cfg)
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://app.localhost/login", nil))
+	if got, want := rr.Header().Get("Location"), "https://app.localhost/dashboard?x=1"; got != want {
+		t.Errorf("Location = %q, want %q", got, want)
+	}
+	if got, want := rr.Header().Get("Refresh"), "5; url=https://app.localhost/home"; got != want {
+		t.Errorf("Refresh = %q, want %q", got, want)
+	}
+	wantCookies := []string{"sid=1; Path=/; HttpOnly; Secure", "theme=dark; Domain=example.com; Secure"}
+	if got := rr.Header().Values("Set-Cookie"); !reflect.DeepEqual(got, wantCookies) {
+		t.Errorf("Set-Cookie = %q, want %q", got, wantCookies)
+	}
+
+	wantBody := `<a href="https://web.localhost/a">a</a><script src="https://web.localhost/app.js"></script>` +
+		`<script>new WebSocket("wss://web.localhost/hmr")</script>` +
+		`<a href="` + origin + `0/other">keep</a>`
+
+	for _, encoding := range []string{"", "gzip"} {
+		req := newLocalRequest("GET", "https://web.localhost/page", nil)
+		if encoding != "" {
+			req.Header.Set("Accept-Encoding", encoding)
+		}
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+
+		var body io.Reader = rr.Body
+		if rr.Header().Get("Content-Encoding") != encoding {
+			t.Fatalf("Content-Encoding = %q, want %q", rr.Header().Get("Content-Encoding"), encoding)
+		}
+		if encoding == "gzip" {
+			gz, err := gzip.NewReader(rr.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = gz
+		}
+		got, _ := io.ReadAll(body)
+		if string(got) != wantBody {
+			t.Errorf("body (%q) = %s, want %s", encoding, got, wantBody)
+		}
+		if etag := rr.Header().Get("ETag"); etag != `W/"v1"` {
+			t.Errorf("ETag = %q, want weak validator", etag)
+		}
+	}
+}
+
+func TestURLRewriterChunkBoundaries(t *testing.T) {
+	u := &urlRewriter{}
+	u.add("http://localhost:3000", "https://3000.localhost")
+	u.add("http://localhost", "https://80.localhost")
+
+	input := "go http://localhost:3000/x and http://localhost/y but not http://localhost:30001 or http://localhost.dev"
+	want := "go https://3000.localhost/x and https://80.localhost/y but not http://localhost:30001 or http://localhost.dev"
+
+	var out bytes.Buffer
+	if err := u.copy(&out, iotest.OneByteReader(strings.NewReader(input))); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want {
+		t.Errorf("copy() = %q, want %q", out.String(), want)
+	}
+}
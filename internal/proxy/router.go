@@ -0,0 +1,255 @@
+package proxy
+
+import (
+	"cmp"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+	tlsutil "github.com/imcanugur/httpsify/internal/tls"
+)
+
+type route struct {
+	name      string
+	path      string
+	strip     bool
+	host      string
+	port      int
+	socket    string
+	dir       string
+	spa       bool
+	listing   bool
+	scheme    string
+	target    *url.URL
+	tlsConfig *tls.Config
+	transport http.RoundTripper
+	proxy     *httputil.ReverseProxy
+	allowHTTP bool
+	hold      time.Duration
+	headers   *config.HeaderRules
+	rewrite   bool
+	identity  string
+	cors      *config.CORSPolicy
+	clients   *config.ClientACL
+	auth      *config.RouteAuth
+	err       error
+}
+
+func (s *Server) newRoute(rc config.Route) *route {
+	rt := &route{
+		name:      rc.Name,
+		path:      strings.TrimSuffix(rc.Path, "/"),
+		strip:     rc.StripPrefix,
+		host:      rc.UpstreamHost(),
+		port:      rc.Port,
+		socket:    rc.Socket,
+		dir:       rc.Dir,
+		spa:       rc.SPA,
+		listing:   rc.Listing,
+		scheme:    rc.Scheme,
+		allowHTTP: rc.AllowHTTP,
+		headers:   rc.Headers,
+		rewrite:   rc.RewriteBody || s.cfg.RewriteBody,
+		cors:      rc.CORS,
+		auth:      rc.Auth,
+		transport: s.transport,
+	}
+
+	rt.target = &url.URL{Scheme: "http", Host: net.JoinHostPort(rt.host, strconv.Itoa(rt.port))}
+	if rt.socket != "" {
+		rt.target.Host = s.socketHost(rt.socket)
+	}
+
+	if rc.IsTLS() {
+		rt.target.Scheme = "https"
+		rt.tlsConfig, rt.err = upstreamTLSConfig(rc)
+		if rt.err == nil {
+			tr := s.transport.Clone()
+			tr.TLSClientConfig = rt.tlsConfig
+			tr.ForceAttemptHTTP2 = true
+			rt.transport = tr
+		}
+	}
+	if rc.IsH2C() {
+		rt.transport = newH2CTransport(s.transport)
+	}
+	rt.hold = time.Duration(cmp.Or(rc.HoldTimeout, s.cfg.HoldTimeout)) * time.Second
+	if rt.hold > 0 {
+		rt.transport = &holdTransport{s: s, rt: rt, next: rt.transport}
+	}
+	rt.identity = identityHost(rc, rt.target.Host)
+	if rt.cors == nil {
+		rt.cors = s.cfg.CORS
+	}
+	if len(rc.AllowClients) > 0 {
+		acl, _ := config.ParseClientACL(rc.AllowClients)
+		rt.clients = &acl
+	}
+
+	rt.proxy = s.newReverseProxy(rt)
+	return rt
+}
+
+func upstreamTLSConfig(rc config.Route) (*tls.Config, error) {
+	opts := tlsutil.ClientOptions{ServerName: rc.UpstreamHost()}
+	if rc.Socket != "" {
+		opts.ServerName = "localhost"
+	}
+	if t := rc.TLS; t != nil {
+		opts.CAPath, opts.Insecure = t.CA, t.Insecure
+		opts.CertPath, opts.KeyPath = t.ClientCert, t.ClientKey
+		if t.ServerName != "" {
+			opts.ServerName = t.ServerName
+		}
+	}
+	return tlsutil.LoadClientConfig(opts)
+}
+
+func (s *Server) portRoute(port int) *route {
+	scheme, ok := s.portSchemes.Load(port)
+	if !ok {
+		scheme = s.detectScheme(port, false)
+	}
+	rc := config.Route{Port: port}
+	rc.Scheme, _ = scheme.(string)
+	if v, ok := s.activePorts.Load(port); ok {
+		if rt := v.(*route); rt.scheme == rc.Scheme {
+			return rt
+		}
+	}
+
+	if rc.IsTLS() {
+		rc.TLS = &config.UpstreamTLS{Insecure: true}
+	}
+	rt := s.newRoute(rc)
+	s.activePorts.Store(port, rt)
+	return rt
+}
+
+func (s *Server) detectScheme(port int, h2c bool) string {
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", addr, 250*time.Millisecond)
+	if err != nil {
+		s.portSchemes.Delete(port)
+		return ""
+	}
+	conn.Close()
+
+	scheme := "http"
+	if s.speaksTLS(addr) {
+		scheme = "https"
+	} else if h2c {
+		// The h2c probe reaches the backend's handlers, so it only runs after a plain request failed.
+		if _, ok := s.probeH2CService(port); ok {
+			scheme = "h2c"
+		}
+	}
+	s.portSchemes.Store(port, scheme)
+	return scheme
+}
+
+func (s *Server) reprobeScheme(rt *route, err error) {
+	if v, ok := s.activePorts.Load(rt.port); !ok || v.(*route) != rt {
+		return
+	}
+	var recordErr tls.RecordHeaderError
+	if backendDown(err) || connDropped(err) || errors.As(err, &recordErr) || strings.Contains(err.Error(), "malformed HTTP") {
+		s.detectScheme(rt.port, true)
+	}
+}
+
+func (rt *route) matches(path string) bool {
+	if rt.path == "" {
+		return true
+	}
+	return path == rt.path || strings.HasPrefix(path, rt.path+"/")
+}
+
+func (rt *route) label() string {
+	if rt.name == "" {
+		return ""
+	}
+	return rt.name + rt.path
+}
+
+func (rt *route) usesPort() bool {
+	return rt.socket == "" && rt.dir == ""
+}
+
+func (rt *route) network() string {
+	if rt.socket != "" {
+		return "unix"
+	}
+	return "tcp"
+}
+
+func (rt *route) dialAddr() string {
+	if rt.socket != "" {
+		return rt.socket
+	}
+	return rt.target.Host
+}
+
+func (rt *route) upstream() string {
+	if rt.socket != "" {
+		return "socket " + rt.socket
+	}
+	if rt.dir != "" {
+		return "directory " + rt.dir
+	}
+	if config.IsLoopbackHost(rt.host) {
+		return fmt.Sprintf("port %d", rt.port)
+	}
+	return rt.target.Host
+}
+
+func (rt *route) rewritePath(req *http.Request) {
+	if !rt.strip || rt.path == "" {
+		return
+	}
+
+	escaped := req.URL.EscapedPath()
+	req.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, rt.path), "/")
+	req.URL.RawPath = ""
+	for i := 1; i <= len(escaped); i++ {
+		if i < len(escaped) && escaped[i] != '/' {
+			continue
+		}
+		if prefix, err := url.PathUnescape(escaped[:i]); err == nil && prefix == rt.path {
+			req.URL.RawPath = "/" + strings.TrimPrefix(escaped[i:], "/")
+			return
+		}
+	}
+}
+
+func (s *Server) buildRoutes() map[string][]*route {
+	routes := make(map[string][]*route)
+	for _, rc := range s.cfg.Routes {
+		routes[rc.Name] = append(routes[rc.Name], s.newRoute(rc))
+	}
+
+	for _, rules := range routes {
+		sort.SliceStable(rules, func(i, j int) bool {
+			return len(rules[i].path) > len(rules[j].path)
+		})
+	}
+	return routes
+}
+
+func (s *Server) matchRoute(name, path string) (*route, bool) {
+	for _, rt := range s.routes[name] {
+		if rt.matches(path) {
+			return rt, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,206 @@
+package proxy
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestUnknownRouteListsAliases(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{{Name: "billing", Port: 8000}}
+	s := newTestServer(t, cfg)
+
+	req := newLocalRequest("GET", "https://nope.localhost/", nil)
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode error response: %v", err)
+	}
+	if !reflect.DeepEqual(resp.KnownRoutes, []string{"billing"}) {
+		t.Errorf("KnownRoutes = %v, want [billing]", resp.KnownRoutes)
+	}
+}
+
+func TestResolveRoute(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{
+		{Name: "billing", Port: 8000},
+		{Name: "shop", Port: 3000},
+		{Name: "app", Path: "/", Port: 3000},
+		{Name: "app", Path: "/api", Port: 8000},
+		{Name: "app", Path: "/api/v2", Port: 8002},
+		{Name: "docs", Path: "/docs", Port: 4000},
+	}
+	s := newTestServer(t, cfg)
+
+	tests := []struct {
+		host      string
+		path      string
+		wantPort  int
+		wantRoute string
+		wantErr   error
+	}{
+		{"billing.localhost", "/", 8000, "billing", nil},
+		{"Shop.localtest.me:443", "/cart", 3000, "shop", nil},
+		{"5173.localhost", "/", 5173, "", nil},
+		{"app.localhost", "/", 3000, "app", nil},
+		{"app.localhost", "/apix", 3000, "app", nil},
+		{"app.localhost", "/api", 8000, "app/api", nil},
+		{"app.localhost", "/api/users", 8000, "app/api", nil},
+		{"app.localhost", "/api/v2/users", 8002, "app/api/v2", nil},
+		{"docs.localhost", "/other", 0, "", errUnknownRoute},
+		{"unknown.localhost", "/", 0, "", errUnknownRoute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host+tt.path, func(t *testing.T) {
+			rt, err := s.resolveRoute(tt.host, tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("resolveRoute(%q, %q) error = %v, want %v", tt.host, tt.path, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if rt.port != tt.wantPort || rt.label() != tt.wantRoute {
+				t.Errorf("resolveRoute(%q, %q) = %d, %q, want %d, %q", tt.host, tt.path, rt.port, rt.label(), tt.wantPort, tt.wantRoute)
+			}
+		})
+	}
+
+	cfg.DefaultRoute = "shop"
+	rt, err := s.resolveRoute("unknown.localhost", "/")
+	if err != nil || rt.port != 3000 || rt.name != "shop" {
+		t.Errorf("resolveRoute() with default route = %v, %v, want shop on 3000", rt, err)
+	}
+}
+
+func TestPathPrefixStripping(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.EscapedPath() + "|" + r.Header.Get("X-Forwarded-Prefix")))
+	}))
+	defer backend.Close()
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{
+		{Name: "app", Path: "/api", StripPrefix: true, Port: port},
+		{Name: "app", Path: "/raw", Port: port},
+		{Name: "app", Path: "/my docs", StripPrefix: true, Port: port},
+	}
+	s := newTestServer(t, cfg)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/users", "/users|/api"},
+		{"/api", "/|/api"},
+		{"/raw/users", "/raw/users|"},
+		{"/api/a%2Fb", "/a%2Fb|/api"},
+		{"/my%20docs/a%2Fb", "/a%2Fb|/my docs"},
+		{"/my%20docs/x%20y", "/x%20y|/my docs"},
+	}
+
+	for _, tt := range tests {
+		req := newLocalRequest("GET", "https://app.localhost"+tt.path, nil)
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+
+		if got := rr.Body.String(); got != tt.want {
+			t.Errorf("GET %s proxied as %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestUpstreamHostDenied(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{{Name: "api", Host: "10.0.0.5", Port: 8080}}
+	s := newTestServer(t, cfg)
+
+	req := newLocalRequest("GET", "https://api.localhost/", nil)
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusForbidden)
+	}
+}
+
+func TestHTTPSUpstream(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secure"))
+	}))
+	defer backend.Close()
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: backend.Certificate().Raw})
+	if err := os.WriteFile(caPath, caPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{
+		{Name: "system", Port: port, Scheme: "https"},
+		{Name: "custom", Port: port, Scheme: "https", TLS: &config.UpstreamTLS{CA: caPath, ServerName: "example.com"}},
+		{Name: "insecure", Port: port, Scheme: "https", TLS: &config.UpstreamTLS{Insecure: true}},
+	}
+	s := newTestServer(t, cfg)
+
+	tests := []struct {
+		route string
+		want  int
+	}{
+		{"system", http.StatusBadGateway},
+		{"custom", http.StatusOK},
+		{"insecure", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := newLocalRequest("GET", "https://"+tt.route+".localhost/", nil)
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("route %s status = %d, want %d", tt.route, rr.Code, tt.want)
+		}
+	}
+}
+
+func TestProbeDetectsTLS(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+	}))
+	defer backend.Close()
+
+	port := backend.Listener.Addr().(*net.TCPAddr).Port
+	s := newTestServer(t, config.DefaultConfig())
+
+	info := s.probeService(port)
+	if !info.TLS || !info.IsWeb {
+		t.Fatalf("probeService() = %+v, want TLS web service", info)
+	}
+
+	req := newLocalRequest("GET", fmt.Sprintf("https://%d.localhost/", port), nil)
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,206 @@
+package proxy
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/imcanugur/httpsify/internal/logging"
+	"github.com/imcanugur/httpsify/internal/version"
+)
+
+//go:embed listing.html
+var listingPageHTML string
+
+var staticTypes = map[string]string{
+	".ico":         "image/x-icon",
+	".map":         "application/json",
+	".md":          "text/markdown; charset=utf-8",
+	".txt":         "text/plain; charset=utf-8",
+	".wasm":        "application/wasm",
+	".webmanifest": "application/manifest+json",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+}
+
+func staticContentType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if t, ok := staticTypes[ext]; ok {
+		return t
+	}
+	return mime.TypeByExtension(ext)
+}
+
+func hiddenPath(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") && part != ".well-known" {
+			return true
+		}
+	}
+	return false
+}
+
+func acceptsHTML(h http.Header) bool {
+	return strings.Contains(h.Get("Accept"), "text/html")
+}
+
+func openStatic(root http.FileSystem, name string) (http.File, fs.FileInfo, error) {
+	f, err := root.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, info, nil
+}
+
+func (s *Server) serveStatic(w http.ResponseWriter, r *http.Request, rt *route) {
+	requestID, _ := r.Context().Value(logging.RequestIDKey).(string)
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		s.handleError(w, r, requestID, http.StatusMethodNotAllowed, "Method not allowed", "Directory routes only serve GET and HEAD", "")
+		return
+	}
+	if rt.headers != nil {
+		applyHeaderOps(w.Header(), rt.headers.Response, rt.headerVars(r, r.Host))
+	}
+
+	name := r.URL.Path
+	if rt.strip && rt.path != "" {
+		name = strings.TrimPrefix(name, rt.path)
+	}
+	name = path.Clean("/" + name)
+
+	root := http.Dir(rt.dir)
+	var f http.File
+	var info fs.FileInfo
+	err := fs.ErrNotExist
+	if !hiddenPath(name) {
+		f, info, err = openStatic(root, name)
+	}
+	if err == nil && info.IsDir() {
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			f.Close()
+			target := r.URL.Path + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		if index, indexInfo, indexErr := openStatic(root, path.Join(name, "index.html")); indexErr == nil {
+			f.Close()
+			f, info, name = index, indexInfo, path.Join(name, "index.html")
+		} else if rt.listing {
+			defer f.Close()
+			s.serveListing(w, r, f)
+			return
+		} else {
+			f.Close()
+			err = fs.ErrNotExist
+		}
+	}
+	if err != nil && rt.spa && (path.Ext(name) == "" || acceptsHTML(r.Header)) {
+		if f, info, err = openStatic(root, "/index.html"); err == nil {
+			name = "/index.html"
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			s.handleError(w, r, requestID, http.StatusForbidden, fmt.Sprintf("%s is not readable", name), "Check the file permissions in "+rt.dir, "")
+			return
+		}
+		s.handleError(w, r, requestID, http.StatusNotFound, fmt.Sprintf("%s not found", name), "No such file in the route's directory", "")
+		return
+	}
+	defer f.Close()
+
+	contentType := staticContentType(name)
+	etag := fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+	if gz, gzInfo, err := openStatic(root, name+".gz"); err == nil {
+		defer gz.Close()
+		w.Header().Add("Vary", "Accept-Encoding")
+		if gzInfo.Mode().IsRegular() && acceptsGzip(r.Header) {
+			f, info = gz, gzInfo
+			etag = fmt.Sprintf(`"%x-%x-gz"`, info.ModTime().UnixNano(), info.Size())
+			w.Header().Set("Content-Encoding", "gzip")
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+		}
+	}
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+func (s *Server) serveListing(w http.ResponseWriter, r *http.Request, dir http.File) {
+	entries, err := dir.Readdir(-1)
+	if err != nil {
+		requestID, _ := r.Context().Value(logging.RequestIDKey).(string)
+		s.handleError(w, r, requestID, http.StatusInternalServerError, "Failed to read directory", err.Error(), "")
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir() != entries[j].IsDir() {
+			return entries[i].IsDir()
+		}
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	var list strings.Builder
+	if r.URL.Path != "/" {
+		list.WriteString(`
+            <a href="../" class="port-item"><span class="port-name">../</span></a>`)
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		size := formatSize(e.Size())
+		if e.IsDir() {
+			name += "/"
+			size = ""
+		}
+		list.WriteString(fmt.Sprintf(`
+            <a href="%s" class="port-item"><span class="port-name">%s</span><span class="port-action">%s</span></a>`,
+			html.EscapeString((&url.URL{Path: "./" + name}).String()), html.EscapeString(name), size))
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	output := strings.NewReplacer(
+		"{{.PATH}}", html.EscapeString(r.URL.Path),
+		"{{.ENTRIES}}", list.String(),
+		"{{.VERSION}}", version.Get().Version,
+	).Replace(listingPageHTML)
+	w.Write([]byte(output))
+}
+
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
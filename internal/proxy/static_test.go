@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestStaticRoute(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.html":      "<h1>app</h1>",
+		"app.js":          "console.log('app')",
+		"app.js.gz":       "gzipped",
+		"style.css":       "body{}",
+		"font.woff2":      "wOF2",
+		"docs/guide.txt":  "0123456789",
+		"assets/logo.svg": "<svg/>",
+		".env":            "SECRET=1",
+		"assets/.hidden":  "x",
+	}
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{
+		{Name: "app", Dir: dir, SPA: true},
+		{Name: "docs", Dir: dir, Listing: true},
+		{Name: "site", Path: "/static", StripPrefix: true, Dir: dir},
+	}
+	s := newTestServer(t, cfg)
+
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		headers  map[string]string
+		status   int
+		body     string
+		wantHead map[string]string
+	}{
+		{"index", "GET", "https://app.localhost/", nil, 200, "<h1>app</h1>", map[string]string{"Content-Type": "text/html; charset=utf-8"}},
+		{"mime css", "GET", "https://app.localhost/style.css", nil, 200, "body{}", map[string]string{"Content-Type": "text/css; charset=utf-8"}},
+		{"mime woff2", "GET", "https://app.localhost/font.woff2", nil, 200, "wOF2", map[string]string{"Content-Type": "font/woff2"}},
+		{"gzip sidecar", "GET", "https://app.localhost/app.js", map[string]string{"Accept-Encoding": "gzip, br"}, 200, "gzipped", map[string]string{"Content-Encoding": "gzip", "Content-Type": "text/javascript; charset=utf-8", "Vary": "Accept-Encoding"}},
+		{"no gzip", "GET", "https://app.localhost/app.js", nil, 200, "console.log('app')", map[string]string{"Content-Encoding": "", "Vary": "Accept-Encoding"}},
+		{"range", "GET", "https://app.localhost/docs/guide.txt", map[string]string{"Range": "bytes=2-5"}, 206, "2345", map[string]string{"Content-Range": "bytes 2-5/10"}},
+		{"spa fallback", "GET", "https://app.localhost/users/42", nil, 200, "<h1>app</h1>", map[string]string{"Cache-Control": "no-cache"}},
+		{"spa html accept", "GET", "https://app.localhost/users/jane.doe", map[string]string{"Accept": "text/html"}, 200, "<h1>app</h1>", nil},
+		{"spa missing asset", "GET", "https://app.localhost/missing.js", nil, 404, "", nil},
+		{"dotfile", "GET", "https://app.localhost/.env", nil, 404, "", nil},
+		{"traversal", "GET", "https://docs.localhost/../../etc/passwd", nil, 404, "", nil},
+		{"method", "POST", "https://app.localhost/app.js", nil, 405, "", map[string]string{"Allow": "GET, HEAD"}},
+		{"dir redirect", "GET", "https://docs.localhost/assets?x=1", nil, 301, "", map[string]string{"Location": "/assets/?x=1"}},
+		{"listing", "GET", "https://docs.localhost/assets/", nil, 200, "", map[string]string{"Content-Type": "text/html; charset=utf-8"}},
+		{"no listing", "GET", "https://site.localhost/static/assets/", nil, 404, "", nil},
+		{"strip prefix", "GET", "https://site.localhost/static/assets/logo.svg", nil, 200, "<svg/>", map[string]string{"Content-Type": "image/svg+xml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newLocalRequest(tt.method, tt.url, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+			s.ServeHTTP(rr, req)
+			if rr.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rr.Code, tt.status, rr.Body.String())
+			}
+			if tt.body != "" && rr.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", rr.Body.String(), tt.body)
+			}
+			for k, v := range tt.wantHead {
+				if got := rr.Header().Get(k); got != v {
+					t.Errorf("%s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://docs.localhost/assets/", nil))
+	if body := rr.Body.String(); !strings.Contains(body, "logo.svg") || strings.Contains(body, ".hidden") {
+		t.Errorf("listing = %s, want logo.svg without dotfiles", body)
+	}
+
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, newLocalRequest("GET", "https://app.localhost/style.css", nil))
+	etag := rr.Header().Get("ETag")
+	req := newLocalRequest("GET", "https://app.localhost/style.css", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+	if etag == "" || rr.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match %q = %d, want %d", etag, rr.Code, http.StatusNotModified)
+	}
+}
@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+var errHelloPeeked = errors.New("client hello peeked")
+
+type helloConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c helloConn) Read(b []byte) (int, error)  { return c.r.Read(b) }
+func (c helloConn) Write(b []byte) (int, error) { return 0, io.ErrClosedPipe }
+
+type prefixConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *prefixConn) Read(b []byte) (int, error) { return c.r.Read(b) }
+
+func peekClientHello(conn net.Conn) (*tls.ClientHelloInfo, net.Conn, error) {
+	var buf bytes.Buffer
+	var hello *tls.ClientHelloInfo
+
+	err := tls.Server(helloConn{Conn: conn, r: io.TeeReader(conn, &buf)}, &tls.Config{
+		GetConfigForClient: func(h *tls.ClientHelloInfo) (*tls.Config, error) {
+			hello = h
+			return nil, errHelloPeeked
+		},
+	}).Handshake()
+	if hello == nil {
+		return nil, nil, fmt.Errorf("failed to read client hello: %w", err)
+	}
+
+	return hello, &prefixConn{Conn: conn, r: io.MultiReader(&buf, conn)}, nil
+}
+
+func (s *Server) ServeTCP(ln net.Listener, tlsCfg *tls.Config) error {
+	tlsCfg = tlsCfg.Clone()
+	tlsCfg.NextProtos = nil
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handleTCP(conn, tlsCfg)
+	}
+}
+
+func (s *Server) handleTCP(conn net.Conn, tlsCfg *tls.Config) {
+	defer conn.Close()
+	start := time.Now()
+	requestID := s.generateRequestID()
+
+	conn.SetDeadline(start.Add(time.Duration(s.cfg.ReadHeaderTimeout) * time.Second))
+	hello, conn, err := peekClientHello(conn)
+	if err != nil {
+		s.logger.InvalidHost(requestID, "", err.Error())
+		return
+	}
+
+	host := hello.ServerName
+	rt, err := s.resolveRoute(host, "/")
+	if err != nil {
+		s.logger.InvalidHost(requestID, host, err.Error())
+		return
+	}
+
+	if ip := remoteIP(conn.RemoteAddr().String()); !s.clientAllowed(ip, config.ListenerTCP, rt) {
+		s.logger.ClientDenied(requestID, ip.String(), host, "client not in allow list")
+		return
+	}
+
+	port := rt.port
+	if rt.socket == "" && !s.cfg.IsPortAllowed(port) {
+		s.logger.PortDenied(requestID, port, "port in deny list or outside allow range")
+		return
+	}
+	if rt.socket == "" && !s.cfg.IsUpstreamAllowed(rt.host, port) {
+		s.logger.UpstreamDenied(requestID, rt.host, port, "host not in allow list")
+		return
+	}
+
+	mode := "terminate"
+	if s.cfg.IsTCPPassthrough(port) {
+		mode = "passthrough"
+	} else {
+		tlsConn := tls.Server(conn, tlsCfg)
+		if err := tlsConn.Handshake(); err != nil {
+			s.logger.ProxyError(requestID, port, fmt.Errorf("tls handshake failed: %w", err))
+			return
+		}
+		conn = tlsConn
+	}
+	conn.SetDeadline(time.Time{})
+
+	if rt.err != nil {
+		s.logger.ProxyError(requestID, port, rt.err)
+		return
+	}
+
+	var backend net.Conn
+	if mode == "passthrough" {
+		backend, err = s.dialer.Dial(rt.network(), rt.dialAddr())
+	} else {
+		backend, err = s.dialUpstream(context.Background(), rt)
+	}
+	if err != nil {
+		s.logger.ProxyError(requestID, port, err)
+		return
+	}
+	defer backend.Close()
+
+	in, out := pipe(conn, backend)
+	s.logger.TCPSession(requestID, host, port, mode, in, out, time.Since(start))
+}
+
+func pipe(client, backend net.Conn) (in, out int64) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		out, _ = io.Copy(client, backend)
+		client.Close()
+	}()
+
+	go func() {
+		defer wg.Done()
+		in, _ = io.Copy(backend, client)
+		backend.Close()
+	}()
+
+	wg.Wait()
+	return in, out
+}
@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/imcanugur/httpsify/internal/config"
+	tlsutil "github.com/imcanugur/httpsify/internal/tls"
+)
+
+func TestTCPRouting(t *testing.T) {
+	dir := t.TempDir()
+	tlsCfg, err := tlsutil.LoadOrGenerateCert(tlsutil.Config{
+		CertPath:   filepath.Join(dir, "cert.pem"),
+		KeyPath:    filepath.Join(dir, "key.pem"),
+		SelfSigned: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	echo := func(ln net.Listener) {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}
+
+	plain, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	go echo(plain)
+
+	secure, err := tls.Listen("tcp", "127.0.0.1:0", tlsCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer secure.Close()
+	go echo(secure)
+
+	plainPort := plain.Addr().(*net.TCPAddr).Port
+	securePort := secure.Addr().(*net.TCPAddr).Port
+
+	denied, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer denied.Close()
+	go echo(denied)
+	deniedPort := denied.Addr().(*net.TCPAddr).Port
+
+	cfg := config.DefaultConfig()
+	cfg.TCPPassthrough = []config.PortRange{{Start: securePort, End: securePort}}
+	cfg.DenyPorts = append(cfg.DenyPorts, config.PortRange{Start: deniedPort, End: deniedPort})
+	s := newTestServer(t, cfg)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go s.ServeTCP(ln, tlsCfg)
+
+	tests := []struct {
+		name   string
+		port   int
+		wantOK bool
+	}{
+		{"terminate", plainPort, true},
+		{"passthrough", securePort, true},
+		{"denied", deniedPort, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{
+				ServerName:         fmt.Sprintf("%d.localhost", tt.port),
+				InsecureSkipVerify: true,
+			})
+			if err != nil {
+				if tt.wantOK {
+					t.Fatalf("dial failed: %v", err)
+				}
+				return
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+			_, err = conn.Write([]byte("PING\r\n"))
+			buf := make([]byte, 6)
+			if err == nil {
+				_, err = io.ReadFull(conn, buf)
+			}
+			if tt.wantOK && (err != nil || string(buf) != "PING\r\n") {
+				t.Errorf("echo = %q, %v; want PING", buf, err)
+			}
+			if !tt.wantOK && err == nil {
+				t.Errorf("expected connection to port %d to be refused", tt.port)
+			}
+		})
+	}
+}
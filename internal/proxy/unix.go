@@ -0,0 +1,205 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ignoredSocketPrefixes = []string{
+	"/run/systemd/",
+	"/run/dbus/",
+	"/var/run/dbus/",
+	"/run/user/",
+	"/tmp/.X11-unix/",
+	"/tmp/.ICE-unix/",
+}
+
+var blockedSocketNames = []string{
+	// Databases
+	".s.PGSQL.*",     // PostgreSQL
+	"mysql*.sock",    // MySQL, MariaDB, X Protocol
+	"mongodb-*.sock", // MongoDB
+	"redis*.sock",    // Redis
+
+	// Cache, Queues & Runtimes
+	"memcached*.sock", // Memcached
+	"php*fpm*.sock",   // PHP-FPM
+	"docker.sock",     // Docker Engine
+	"containerd.sock", // containerd
+
+	// Agents
+	"agent.*",      // ssh-agent
+	"S.gpg-agent*", // gpg-agent
+}
+
+const socketProbeTTL = 30 * time.Second
+
+type socketProbe struct {
+	inode uint64
+	at    time.Time
+	info  ServiceInfo
+}
+
+func (s *Server) socketHost(path string) string {
+	h := fnv.New32a()
+	h.Write([]byte(path))
+	host := fmt.Sprintf("%08x.sock", h.Sum32())
+	s.sockets.Store(host, path)
+	return host
+}
+
+func (s *Server) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			if path, ok := s.sockets.Load(host); ok {
+				return dialer.DialContext(ctx, "unix", path.(string))
+			}
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+func (s *Server) dialUpstream(ctx context.Context, rt *route) (net.Conn, error) {
+	conn, err := s.dialer.DialContext(ctx, rt.network(), rt.dialAddr())
+	if err != nil || rt.tlsConfig == nil {
+		return conn, err
+	}
+
+	cfg := rt.tlsConfig.Clone()
+	cfg.NextProtos = []string{"http/1.1"}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func (s *Server) GetListeningSockets() []ServiceInfo {
+	data, err := os.ReadFile("/proc/net/unix")
+	if err != nil {
+		return nil
+	}
+
+	listening := make(map[string]uint64)
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if i == 0 || strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		// Num RefCount Protocol Flags Type St Inode Path
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			continue
+		}
+
+		flags, typ, state, path := fields[3], fields[4], fields[5], fields[7]
+		if flags != "00010000" || typ != "0001" || state != "01" {
+			continue
+		}
+		if _, ok := listening[path]; ok || !strings.HasPrefix(path, "/") || isIgnoredSocket(path) {
+			continue
+		}
+		listening[path], _ = strconv.ParseUint(fields[6], 10, 64)
+	}
+
+	return s.probeSockets(listening, s.getInodeProcessMap())
+}
+
+func (s *Server) probeSockets(listening map[string]uint64, procMap map[uint64]string) []ServiceInfo {
+	s.pruneSockets(listening)
+
+	var services []ServiceInfo
+	var mu sync.Mutex
+
+	const maxWorkers = 8
+	jobs := make(chan string, len(listening))
+
+	var wg sync.WaitGroup
+	for i := 0; i < maxWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				info := s.probeSocket(path, listening[path])
+				info.ProcessName = procMap[listening[path]]
+				mu.Lock()
+				services = append(services, info)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for path := range listening {
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+
+	return services
+}
+
+func (s *Server) probeSocket(path string, inode uint64) ServiceInfo {
+	if isBlockedSocket(path) {
+		return ServiceInfo{Socket: path}
+	}
+	if v, ok := s.socketProbes.Load(path); ok {
+		if p := v.(socketProbe); p.inode == inode && time.Since(p.at) < socketProbeTTL {
+			return p.info
+		}
+	}
+	info := s.probeURL(ServiceInfo{Socket: path}, fmt.Sprintf("http://%s", s.socketHost(path)))
+	s.socketProbes.Store(path, socketProbe{inode: inode, at: time.Now(), info: info})
+	return info
+}
+
+func (s *Server) pruneSockets(listening map[string]uint64) {
+	// Sockets behind configured routes stay registered while their service restarts.
+	routed := make(map[string]bool)
+	for _, rc := range s.cfg.Routes {
+		if rc.Socket != "" {
+			routed[rc.Socket] = true
+		}
+	}
+	s.socketProbes.Range(func(k, _ any) bool {
+		if _, ok := listening[k.(string)]; !ok {
+			s.socketProbes.Delete(k)
+		}
+		return true
+	})
+	s.sockets.Range(func(k, v any) bool {
+		if _, ok := listening[v.(string)]; !ok && !routed[v.(string)] {
+			s.sockets.Delete(k)
+		}
+		return true
+	})
+}
+
+func isBlockedSocket(path string) bool {
+	name := filepath.Base(path)
+	for _, pattern := range blockedSocketNames {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isIgnoredSocket(path string) bool {
+	for _, prefix := range ignoredSocketPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/imcanugur/httpsify/internal/config"
+)
+
+func TestUnixSocketRoute(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "app.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets not supported: %v", err)
+	}
+
+	backend := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("socket:" + r.Host + r.URL.Path))
+	})}
+	go backend.Serve(ln)
+	defer backend.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Routes = []config.Route{{Name: "app", Socket: sock}}
+	s := newTestServer(t, cfg)
+
+	req := newLocalRequest("GET", "https://app.localhost/health", nil)
+	rr := httptest.NewRecorder()
+	s.ServeHTTP(rr, req)
+
+	if got, want := rr.Body.String(), "socket:app.localhost/health"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestProbeSockets(t *testing.T) {
+	dir := t.TempDir()
+	hits := make(map[string]int)
+	var mu sync.Mutex
+	listen := func(name string) string {
+		path := filepath.Join(dir, name)
+		ln, err := net.Listen("unix", path)
+		if err != nil {
+			t.Skipf("unix sockets not supported: %v", err)
+		}
+		backend := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			hits[name]++
+			mu.Unlock()
+		})}
+		go backend.Serve(ln)
+		t.Cleanup(func() { backend.Close() })
+		return path
+	}
+	app, pg := listen("app.sock"), listen(".s.PGSQL.5432")
+
+	s := newTestServer(t, config.DefaultConfig())
+	listening := map[string]uint64{app: 1, pg: 2}
+	for i := 0; i < 2; i++ {
+		if services := s.probeSockets(listening, nil); len(services) != 2 {
+			t.Fatalf("probeSockets = %+v", services)
+		}
+	}
+	mu.Lock()
+	if hits["app.sock"] != 1 || hits[".s.PGSQL.5432"] != 0 {
+		t.Errorf("probe hits = %v, want one for app.sock and none for PostgreSQL", hits)
+	}
+	mu.Unlock()
+
+	s.probeSockets(map[string]uint64{pg: 2}, nil)
+	if _, ok := s.socketProbes.Load(app); ok {
+		t.Error("probe result kept for a closed socket")
+	}
+	s.sockets.Range(func(_, v any) bool {
+		if v == app {
+			t.Error("closed socket still registered")
+		}
+		return true
+	})
+}
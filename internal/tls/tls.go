@@ -13,13 +13,17 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
+var DefaultDomains = []string{"localhost", "localtest.me"}
+
 type Config struct {
 	CertPath   string
 	KeyPath    string
 	SelfSigned bool
+	Domains    []string
 }
 
 func LoadOrGenerateCert(cfg Config) (*tls.Config, error) {
@@ -30,14 +34,79 @@ func LoadOrGenerateCert(cfg Config) (*tls.Config, error) {
 	certExists := fileExists(cfg.CertPath)
 	keyExists := fileExists(cfg.KeyPath)
 
+	domains := cfg.Domains
+	if len(domains) == 0 {
+		domains = DefaultDomains
+	}
+
 	if certExists && keyExists {
 		tlsConfig, err := loadCertificates(cfg.CertPath, cfg.KeyPath)
 		if err == nil {
+			if missing := uncoveredDomains(tlsConfig.Certificates[0], domains); len(missing) > 0 {
+				return nil, fmt.Errorf("certificate %s does not cover %s; issue one that does, or remove %s and %s to generate a new self-signed pair",
+					cfg.CertPath, strings.Join(missing, ", "), cfg.CertPath, cfg.KeyPath)
+			}
 			return tlsConfig, nil
 		}
 	}
 
-	return generateSelfSignedCert(cfg.CertPath, cfg.KeyPath)
+	return generateSelfSignedCert(cfg.CertPath, cfg.KeyPath, domains)
+}
+
+func uncoveredDomains(cert tls.Certificate, domains []string) []string {
+	if len(cert.Certificate) == 0 {
+		return domains
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return domains
+	}
+
+	var missing []string
+	for _, domain := range domains {
+		if leaf.VerifyHostname(domain) != nil || leaf.VerifyHostname("sub."+domain) != nil {
+			missing = append(missing, "*."+domain)
+		}
+	}
+	return missing
+}
+
+type ClientOptions struct {
+	CAPath     string
+	CertPath   string
+	KeyPath    string
+	ServerName string
+	Insecure   bool
+}
+
+func LoadClientConfig(opts ClientOptions) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.Insecure,
+	}
+
+	if opts.CAPath != "" {
+		data, err := os.ReadFile(opts.CAPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CAPath)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if opts.CertPath != "" || opts.KeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertPath, opts.KeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
 }
 
 func loadCertificates(certPath, keyPath string) (*tls.Config, error) {
@@ -49,7 +118,7 @@ func loadCertificates(certPath, keyPath string) (*tls.Config, error) {
 	return createTLSConfig(cert), nil
 }
 
-func generateSelfSignedCert(certPath, keyPath string) (*tls.Config, error) {
+func generateSelfSignedCert(certPath, keyPath string, domains []string) (*tls.Config, error) {
 	certDir := filepath.Dir(certPath)
 	if err := os.MkdirAll(certDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create cert directory: %w", err)
@@ -60,7 +129,7 @@ func generateSelfSignedCert(certPath, keyPath string) (*tls.Config, error) {
 		return nil, err
 	}
 
-	leafKey, leafCertDER, err := generateLeafCert(caCert, caKey)
+	leafKey, leafCertDER, err := generateLeafCert(caCert, caKey, domains)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +179,7 @@ func generateCA() (*ecdsa.PrivateKey, []byte, *x509.Certificate, error) {
 	return caKey, caCertDER, caCert, nil
 }
 
-func generateLeafCert(caCert *x509.Certificate, caKey *ecdsa.PrivateKey) (*ecdsa.PrivateKey, []byte, error) {
+func generateLeafCert(caCert *x509.Certificate, caKey *ecdsa.PrivateKey, domains []string) (*ecdsa.PrivateKey, []byte, error) {
 	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to generate leaf key: %w", err)
@@ -125,7 +194,7 @@ func generateLeafCert(caCert *x509.Certificate, caKey *ecdsa.PrivateKey) (*ecdsa
 		SerialNumber: serialNumber,
 		Subject: pkix.Name{
 			Organization: []string{"HTTPSify"},
-			CommonName:   "localhost",
+			CommonName:   domains[0],
 		},
 		NotBefore:             time.Now().Add(-time.Hour),
 		NotAfter:              time.Now().AddDate(1, 0, 0),
@@ -133,12 +202,7 @@ func generateLeafCert(caCert *x509.Certificate, caKey *ecdsa.PrivateKey) (*ecdsa
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
 		BasicConstraintsValid: true,
 		IsCA:                  false,
-		DNSNames: []string{
-			"localhost",
-			"*.localhost",
-			"localtest.me",
-			"*.localtest.me",
-		},
+		DNSNames:              dnsNames(domains),
 		IPAddresses: []net.IP{
 			net.IPv4(127, 0, 0, 1),
 			net.IPv6loopback,
@@ -153,6 +217,14 @@ func generateLeafCert(caCert *x509.Certificate, caKey *ecdsa.PrivateKey) (*ecdsa
 	return leafKey, leafCertDER, nil
 }
 
+func dnsNames(domains []string) []string {
+	names := make([]string, 0, len(domains)*2)
+	for _, domain := range domains {
+		names = append(names, domain, "*."+domain)
+	}
+	return names
+}
+
 func writeCertFiles(certPath, keyPath, certDir string, leafCertDER, caCertDER []byte, leafKey *ecdsa.PrivateKey) error {
 	certFile, err := os.OpenFile(certPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
@@ -1,8 +1,11 @@
 package tlsutil
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -112,3 +115,83 @@ func TestFileExists(t *testing.T) {
 		t.Error("fileExists() = true for non-existent file")
 	}
 }
+
+func TestLoadClientConfig(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "httpsify-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	certPath := filepath.Join(tmpDir, "localhost.pem")
+	keyPath := filepath.Join(tmpDir, "localhost-key.pem")
+	if _, err := LoadOrGenerateCert(Config{CertPath: certPath, KeyPath: keyPath, SelfSigned: true}); err != nil {
+		t.Fatalf("LoadOrGenerateCert() error = %v", err)
+	}
+
+	clientCfg, err := LoadClientConfig(ClientOptions{
+		CAPath:     filepath.Join(tmpDir, "ca.pem"),
+		CertPath:   certPath,
+		KeyPath:    keyPath,
+		ServerName: "localhost",
+	})
+	if err != nil {
+		t.Fatalf("LoadClientConfig() error = %v", err)
+	}
+	if clientCfg.RootCAs == nil {
+		t.Error("LoadClientConfig() did not set RootCAs")
+	}
+	if len(clientCfg.Certificates) != 1 {
+		t.Errorf("Expected 1 client certificate, got %d", len(clientCfg.Certificates))
+	}
+	if clientCfg.InsecureSkipVerify {
+		t.Error("LoadClientConfig() enabled InsecureSkipVerify")
+	}
+
+	if _, err := LoadClientConfig(ClientOptions{CAPath: keyPath}); err == nil {
+		t.Error("LoadClientConfig() expected error for CA file without certificates")
+	}
+}
+
+func TestCertificateCoversDomains(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "httpsify-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cfg := Config{
+		CertPath:   filepath.Join(tmpDir, "localhost.pem"),
+		KeyPath:    filepath.Join(tmpDir, "localhost-key.pem"),
+		SelfSigned: true,
+	}
+
+	tlsCfg, err := LoadOrGenerateCert(cfg)
+	if err != nil {
+		t.Fatalf("LoadOrGenerateCert() error = %v", err)
+	}
+	if missing := uncoveredDomains(tlsCfg.Certificates[0], []string{"localhost", "test"}); !reflect.DeepEqual(missing, []string{"*.test"}) {
+		t.Fatalf("uncoveredDomains() = %v, want [*.test]", missing)
+	}
+
+	before, err := os.ReadFile(cfg.CertPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Domains = []string{"localhost", "test"}
+	if _, err := LoadOrGenerateCert(cfg); err == nil || !strings.Contains(err.Error(), "*.test") {
+		t.Errorf("LoadOrGenerateCert() error = %v, want uncovered *.test", err)
+	}
+	if after, _ := os.ReadFile(cfg.CertPath); !bytes.Equal(before, after) {
+		t.Error("existing certificate was overwritten")
+	}
+
+	os.Remove(cfg.CertPath)
+	tlsCfg, err = LoadOrGenerateCert(cfg)
+	if err != nil {
+		t.Fatalf("LoadOrGenerateCert() error = %v", err)
+	}
+	if missing := uncoveredDomains(tlsCfg.Certificates[0], cfg.Domains); len(missing) > 0 {
+		t.Errorf("generated certificate does not cover %v", missing)
+	}
+}